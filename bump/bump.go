@@ -0,0 +1,143 @@
+// Package bump updates the container image references in the Dockerfiles of a git repository.
+package bump
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/binxio/fromage/deprecation"
+	"github.com/binxio/fromage/dockerfile"
+	"github.com/binxio/fromage/repository"
+	"github.com/binxio/fromage/tag"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"strings"
+)
+
+// The types of changes, recorded in the commit message.
+const (
+	BumpChange        = "bump"
+	MoveChange        = "move"
+	DeprecationChange = "deprecation"
+	RevertChange      = "revert"
+)
+
+// Result records the changes made to the Dockerfiles in the repository.
+type Result struct {
+	// Type is the type of the changes, like bump or move.
+	Type    string
+	Changes dockerfile.Changes
+	// Reverts is the hash of the commit reverted by the changes, if any.
+	Reverts string
+	patch   bytes.Buffer
+}
+
+// Updated returns true if any reference was updated.
+func (r *Result) Updated() bool {
+	return len(r.Changes) > 0
+}
+
+// Patch returns the changes as a unified diff.
+func (r *Result) Patch() []byte {
+	return r.patch.Bytes()
+}
+
+// Trailers returns the git trailers recording the changes, the levels on which they were pinned and the
+// version of fromage which made them.
+func (r *Result) Trailers(options Options, version string) []string {
+	result := make([]string, 0, len(r.Changes)+5)
+	if r.Type != "" {
+		result = append(result, fmt.Sprintf("%s: %s", repository.TypeTrailer, r.Type))
+	}
+	for _, change := range r.Changes {
+		result = append(result, fmt.Sprintf("%s: %s %s -> %s", repository.ChangeTrailer, change.Path, change.From, change.To))
+	}
+	if r.Reverts != "" {
+		result = append(result, fmt.Sprintf("%s: %s", repository.RevertTrailer, r.Reverts))
+	}
+	if options.Pin != nil {
+		result = append(result, fmt.Sprintf("%s: %s", repository.PinTrailer, strings.ToLower(options.Pin.String())))
+	}
+	if options.VariantPin != nil {
+		result = append(result, fmt.Sprintf("%s: %s", repository.VariantPinTrailer, strings.ToLower(options.VariantPin.String())))
+	}
+	return append(result, fmt.Sprintf("%s: %s", repository.VersionTrailer, version))
+}
+
+// CommitMessage returns the commit message with the subject, followed by the trailers of the result.
+func (r *Result) CommitMessage(subject string, options Options, version string) string {
+	return subject + "\n\n" + strings.Join(r.Trailers(options, version), "\n") + "\n"
+}
+
+type Options struct {
+	Branches    []string
+	Pin         *tag.Level
+	VariantPin  *tag.Level
+	Latest      bool
+	Platforms   tag.Platforms
+	PinFloating bool
+	Stages      dockerfile.StageSelection
+	DryRun      bool
+	Verbose     bool
+}
+
+// Update bumps the container image references in all Dockerfiles on the branches to their next
+// version. With PinFloating, references with a floating tag are first pinned to the version they currently
+// refer to. Only the references selected by Stages are bumped. Changed Dockerfiles are written to the
+// worktree, unless it is a dry run.
+func Update(ctx context.Context, r *repository.Repository, options Options) (*Result, error) {
+	return forEachDockerfile(ctx, r, BumpChange, options, func(content []byte, filename string) ([]byte, dockerfile.Changes, error) {
+		pinned := make(dockerfile.Changes, 0)
+		if options.PinFloating {
+			var err error
+			if content, pinned, err = dockerfile.PinFloatingReferences(content, filename, options.Verbose); err != nil {
+				return nil, nil, err
+			}
+		}
+		content, changes, err := dockerfile.UpdateSelectedFromStatements(content, filename, options.Pin, options.VariantPin, options.Latest,
+			options.Platforms, options.Stages, options.Verbose)
+		if err != nil {
+			return nil, nil, err
+		}
+		return content, append(pinned, changes...), nil
+	})
+}
+
+// Move moves the container image references in all Dockerfiles on the branches from the repository
+// context `from` to the repository context `to`.
+func Move(ctx context.Context, r *repository.Repository, from, to string, options Options) (*Result, error) {
+	return forEachDockerfile(ctx, r, MoveChange, options, func(content []byte, filename string) ([]byte, dockerfile.Changes, error) {
+		return dockerfile.MoveImageReferences(content, filename, options.Verbose, from, to)
+	})
+}
+
+func forEachDockerfile(ctx context.Context, r *repository.Repository, changeType string, options Options, update func([]byte, string) ([]byte, dockerfile.Changes, error)) (*Result, error) {
+	result := &Result{Type: changeType, Changes: make(dockerfile.Changes, 0)}
+	err := r.ForEachDockerfile(ctx, options.Branches, func(branch *plumbing.Reference, filename string, original []byte) error {
+		content, changes, err := update(original, filename)
+		if err != nil {
+			return err
+		}
+
+		if len(changes) > 0 {
+			result.Changes = append(result.Changes, changes.WithBranch(branch.Name().Short())...)
+			dockerfile.WriteUnifiedDiff(&result.patch, filename, original, content)
+			// changes on a branch are only recorded until they are committed, so on a dry run they are
+			// recorded too, to report the branches which would be committed
+			if !options.DryRun || !r.IsWorktree() {
+				return r.WriteFile(branch, filename, content)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// ReplaceDeprecated replaces the references deprecated by the rules with their recommended replacement.
+func ReplaceDeprecated(ctx context.Context, r *repository.Repository, rules deprecation.Rules, options Options) (*Result, error) {
+	return forEachDockerfile(ctx, r, DeprecationChange, options, func(content []byte, filename string) ([]byte, dockerfile.Changes, error) {
+		return dockerfile.ReplaceDeprecatedReferences(content, filename, options.Verbose, rules)
+	})
+}
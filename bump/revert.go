@@ -0,0 +1,184 @@
+package bump
+
+import (
+	"context"
+	"fmt"
+	"github.com/binxio/fromage/dockerfile"
+	"github.com/binxio/fromage/repository"
+	"github.com/google/go-containerregistry/pkg/name"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+	"log"
+)
+
+// revertDepth is the number of commits searched for the commit to revert.
+const revertDepth = 100
+
+// FindRevertable returns the most recent commit of fromage on the branch which is neither a revert nor
+// reverted.
+func FindRevertable(ctx context.Context, r *repository.Repository, branch string) (*object.Commit, error) {
+	if err := r.Deepen(ctx, branch, revertDepth); err != nil {
+		return nil, err
+	}
+	head, err := r.Head(branch)
+	if err != nil {
+		return nil, err
+	}
+
+	var result *object.Commit
+	reverted := make(map[string]bool)
+	count := 0
+	err = r.Log(head, func(commit *object.Commit) (bool, error) {
+		count++
+		if !repository.IsFromageCommit(commit) {
+			return count < revertDepth, nil
+		}
+		if hashes := repository.Trailers(commit.Message, repository.RevertTrailer); len(hashes) > 0 {
+			for _, hash := range hashes {
+				reverted[hash] = true
+			}
+			return count < revertDepth, nil
+		}
+		if reverted[commit.Hash.String()] {
+			return count < revertDepth, nil
+		}
+		result = commit
+		return false, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if result == nil {
+		return nil, fmt.Errorf("no commit by fromage found in the last %d commits of %s", revertDepth, r.Url)
+	}
+	return result, nil
+}
+
+// revertVerbs are the verbs describing the type of the reverted changes in the subject of the revert.
+var revertVerbs = map[string]string{
+	BumpChange:        "bumped",
+	MoveChange:        "moved",
+	DeprecationChange: "replaced",
+}
+
+// RevertMessage returns the subject of the commit reverting the commit, describing the type of its changes as
+// recorded in its trailer.
+func RevertMessage(commit *object.Commit) string {
+	verb := "changed"
+	if types := repository.Trailers(commit.Message, repository.TypeTrailer); len(types) > 0 {
+		if v, ok := revertVerbs[types[0]]; ok {
+			verb = v
+		}
+	}
+	return fmt.Sprintf("reverted container image references %s in %s", verb, commit.Hash.String()[0:7])
+}
+
+// referenceChange is a container image reference changed by a commit, in the FROM statement at index.
+type referenceChange struct {
+	index    int
+	from, to name.Reference
+}
+
+// changedReferences returns the references changed by the commit, by Dockerfile. The FROM statements of the
+// Dockerfile before and after the commit are paired by position.
+func changedReferences(commit *object.Commit) (map[string][]referenceChange, error) {
+	parent, err := commit.Parent(0)
+	if err != nil {
+		return nil, fmt.Errorf("cannot revert %s without parent, %s", commit.Hash.String()[0:7], err)
+	}
+	parentTree, err := parent.Tree()
+	if err != nil {
+		return nil, err
+	}
+	tree, err := commit.Tree()
+	if err != nil {
+		return nil, err
+	}
+	changes, err := object.DiffTree(parentTree, tree)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string][]referenceChange)
+	for _, change := range changes {
+		before, after, err := change.Files()
+		if err != nil {
+			return nil, err
+		}
+		if before == nil || after == nil {
+			continue
+		}
+		// the files of a change only have their base name, the path is in the change itself
+		filename := change.To.Name
+		original, err := before.Contents()
+		if err != nil {
+			return nil, err
+		}
+		updated, err := after.Contents()
+		if err != nil {
+			return nil, err
+		}
+		from := dockerfile.ParseFromStatements([]byte(original))
+		to := dockerfile.ParseFromStatements([]byte(updated))
+		if len(from) != len(to) {
+			log.Printf("WARNING: skipping %s, the number of FROM statements changed in %s", filename,
+				commit.Hash.String()[0:7])
+			continue
+		}
+		for i := range from {
+			if from[i].Reference == to[i].Reference {
+				continue
+			}
+			f, err := name.ParseReference(from[i].Reference)
+			if err != nil {
+				continue
+			}
+			t, err := name.ParseReference(to[i].Reference)
+			if err != nil {
+				continue
+			}
+			result[filename] = append(result[filename], referenceChange{index: i, from: f, to: t})
+		}
+	}
+	return result, nil
+}
+
+// Revert restores the references changed by the commit on the branches. Every FROM statement is restored at
+// its own position, so other statements referring to the same image are left as is. Only the FROM statements
+// are updated, so later changes to the Dockerfiles are preserved. References which were changed again since
+// are skipped. The reverted commit is recorded in the result.
+func Revert(ctx context.Context, r *repository.Repository, commit *object.Commit, options Options) (*Result, error) {
+	changed, err := changedReferences(commit)
+	if err != nil {
+		return nil, err
+	}
+	reason := fmt.Sprintf("revert of %s", commit.Hash.String()[0:7])
+	found := make(map[string]bool, len(changed))
+	result, err := forEachDockerfile(ctx, r, RevertChange, options, func(content []byte, filename string) ([]byte, dockerfile.Changes, error) {
+		found[filename] = true
+		result := make(dockerfile.Changes, 0)
+		for _, change := range changed[filename] {
+			c, changes, err := dockerfile.UpdateFromStatementAt(content, change.index, change.to, change.from, filename, options.Verbose)
+			if err != nil {
+				return nil, nil, err
+			}
+			if len(changes) == 0 {
+				log.Printf("WARNING: FROM statement %d of %s no longer refers to %s, not reverted", change.index+1,
+					filename, change.to)
+				continue
+			}
+			content = c
+			result = append(result, changes.WithReason(reason)...)
+		}
+		return content, result, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for filename := range changed {
+		if !found[filename] {
+			log.Printf("WARNING: %s changed in %s is not found, not reverted", filename, commit.Hash.String()[0:7])
+		}
+	}
+	result.Reverts = commit.Hash.String()
+	return result, nil
+}
@@ -0,0 +1,247 @@
+package bump
+
+import (
+	"bytes"
+	"context"
+	"github.com/binxio/fromage/repository"
+	"gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func commitDockerfile(t *testing.T, r *git.Repository, dir string, content string, author string, email string) {
+	commitDockerfiles(t, r, dir, map[string]string{"Dockerfile": content}, author, email)
+}
+
+func commitDockerfiles(t *testing.T, r *git.Repository, dir string, contents map[string]string, author string, email string) {
+	worktree, err := r.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for filename, content := range contents {
+		if err = os.MkdirAll(filepath.Join(dir, filepath.Dir(filename)), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err = ioutil.WriteFile(filepath.Join(dir, filename), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err = worktree.Add(filename); err != nil {
+			t.Fatal(err)
+		}
+	}
+	_, err = worktree.Commit("update Dockerfiles", &git.CommitOptions{
+		Author: &object.Signature{Name: author, Email: email, When: time.Now()},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRevert(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fromage-revert")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	g, err := git.PlainInit(dir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	commitDockerfile(t, g, dir, "FROM golang:1.12 AS build\nRUN make\nFROM alpine:3.16\n", "test", "test@example.com")
+	commitDockerfile(t, g, dir, "FROM golang:1.13 AS build\nRUN make\nFROM alpine:3.17\n", repository.AuthorName, repository.AuthorEmail)
+	commitDockerfile(t, g, dir, "FROM golang:1.13 AS build\nRUN make test\nFROM alpine:3.18\n", "test", "test@example.com")
+
+	r, err := repository.Open(context.Background(), dir, false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	commit, err := FindRevertable(context.Background(), r, "master")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if commit.Author.Email != repository.AuthorEmail {
+		t.Fatalf("expected the commit of fromage to be reverted, got %s", commit.Author.Email)
+	}
+
+	result, err := Revert(context.Background(), r, commit, Options{Branches: []string{"master"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Changes) != 1 || result.Changes[0].From != "golang:1.13" || result.Changes[0].To != "golang:1.12" {
+		t.Fatalf("expected golang:1.13 to be reverted to golang:1.12, got %v", result.Changes)
+	}
+	if err = r.CommitAndPush(context.Background(), result.CommitMessage(RevertMessage(commit), Options{}, "test"), false); err != nil {
+		t.Fatal(err)
+	}
+
+	head, err := g.Reference(plumbing.NewBranchReferenceName("master"), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reverted, err := g.CommitObject(head.Hash())
+	if err != nil {
+		t.Fatal(err)
+	}
+	file, err := reverted.File("Dockerfile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if content, _ := file.Contents(); content != "FROM golang:1.12 AS build\nRUN make test\nFROM alpine:3.18\n" {
+		t.Fatalf("expected only the reverted FROM statement to change, got\n%s", content)
+	}
+	content, err := ioutil.ReadFile(filepath.Join(dir, "Dockerfile"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "FROM golang:1.12 AS build\nRUN make test\nFROM alpine:3.18\n" {
+		t.Fatalf("expected the checked out Dockerfile to be reverted, got\n%s", string(content))
+	}
+	if _, err = FindRevertable(context.Background(), r, "master"); err == nil {
+		t.Fatalf("expected no commit to revert after the revert")
+	}
+}
+
+func TestRevertChainedBump(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fromage-revert")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	g, err := git.PlainInit(dir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	commitDockerfile(t, g, dir, "FROM golang:1.13 AS build\nFROM golang:1.12 AS test\nFROM golang:1.13\n", "test", "test@example.com")
+	commitDockerfile(t, g, dir, "FROM golang:1.14 AS build\nFROM golang:1.13 AS test\nFROM golang:1.13\n", repository.AuthorName, repository.AuthorEmail)
+
+	r, err := repository.Open(context.Background(), dir, false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	commit, err := FindRevertable(context.Background(), r, "master")
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := Revert(context.Background(), r, commit, Options{Branches: []string{"master"}, DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := "-FROM golang:1.14 AS build\n+FROM golang:1.13 AS build\n" +
+		"-FROM golang:1.13 AS test\n+FROM golang:1.12 AS test\n" +
+		" FROM golang:1.13\n"
+	if patch := string(result.Patch()); !strings.Contains(patch, expect) {
+		t.Fatalf("expected only the bumped statements to be restored, got\n%s", patch)
+	}
+	if len(result.Changes) != 2 || result.Changes[0].Line != 1 || result.Changes[1].Line != 2 {
+		t.Fatalf("expected the changes on line 1 and 2, got %v", result.Changes)
+	}
+}
+
+func TestRevertNestedDockerfile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fromage-revert")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	g, err := git.PlainInit(dir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	commitDockerfiles(t, g, dir, map[string]string{
+		"Dockerfile":       "FROM golang:1.13\n",
+		"svc/a/Dockerfile": "FROM golang:1.12\n",
+	}, "test", "test@example.com")
+	commitDockerfiles(t, g, dir, map[string]string{"svc/a/Dockerfile": "FROM golang:1.13\n"},
+		repository.AuthorName, repository.AuthorEmail)
+
+	r, err := repository.Open(context.Background(), dir, false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	commit, err := FindRevertable(context.Background(), r, "master")
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := Revert(context.Background(), r, commit, Options{Branches: []string{"master"}, DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Changes) != 1 || result.Changes[0].Path != "svc/a/Dockerfile" || result.Changes[0].To != "golang:1.12" {
+		t.Fatalf("expected golang:1.13 in svc/a/Dockerfile to be reverted to golang:1.12, got %v", result.Changes)
+	}
+}
+
+func TestRevertDryRun(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fromage-revert")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	g, err := git.PlainInit(dir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	commitDockerfile(t, g, dir, "FROM golang:1.12\n", "test", "test@example.com")
+	commitDockerfile(t, g, dir, "FROM golang:1.13\n", repository.AuthorName, repository.AuthorEmail)
+	bumped, err := g.Head()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := repository.Open(context.Background(), dir, false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	commit, err := FindRevertable(context.Background(), r, "master")
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := Revert(context.Background(), r, commit, Options{Branches: []string{"master"}, DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var output bytes.Buffer
+	log.SetOutput(&output)
+	defer log.SetOutput(os.Stderr)
+	if err = r.CommitAndPush(context.Background(), result.CommitMessage(RevertMessage(commit), Options{}, "test"), true); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(output.String(), "INFO: changes would be committed on master") {
+		t.Fatalf("expected the dry run to report the branch, got\n%s", output.String())
+	}
+
+	head, err := g.Reference(plumbing.NewBranchReferenceName("master"), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if head.Hash() != bumped.Hash() {
+		t.Fatalf("expected master to be left at %s, got %s", bumped.Hash(), head.Hash())
+	}
+}
+
+func TestRevertMessage(t *testing.T) {
+	tests := []struct {
+		message string
+		expect  string
+	}{
+		{"bumped\n\nFromage-Type: bump\n", "bumped"},
+		{"moved\n\nFromage-Type: move\n", "moved"},
+		{"replaced\n\nFromage-Type: deprecation\n", "replaced"},
+		{"updated Dockerfile\n", "changed"},
+	}
+	for _, test := range tests {
+		commit := &object.Commit{Hash: plumbing.NewHash("0123456789abcdef0123456789abcdef01234567"), Message: test.message}
+		expect := "reverted container image references " + test.expect + " in 0123456"
+		if message := RevertMessage(commit); message != expect {
+			t.Fatalf("expected %q, got %q", expect, message)
+		}
+	}
+}
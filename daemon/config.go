@@ -0,0 +1,44 @@
+package daemon
+
+import (
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"io/ioutil"
+)
+
+// Repository is a repository to scan. If no branches are specified, all branches are scanned.
+type Repository struct {
+	Url      string   `yaml:"url"`
+	Branches []string `yaml:"branches,omitempty"`
+}
+
+// Config lists the repositories to scan.
+type Config struct {
+	Repositories []Repository `yaml:"repositories"`
+}
+
+// Parse reads the configuration from the YAML content.
+func Parse(content []byte) (*Config, error) {
+	var result Config
+	if err := yaml.Unmarshal(content, &result); err != nil {
+		return nil, fmt.Errorf("could not read repositories, %s", err)
+	}
+	if len(result.Repositories) == 0 {
+		return nil, fmt.Errorf("no repositories to scan")
+	}
+	for i, repository := range result.Repositories {
+		if repository.Url == "" {
+			return nil, fmt.Errorf("repository %d has no url", i+1)
+		}
+	}
+	return &result, nil
+}
+
+// Load reads the configuration from the file.
+func Load(filename string) (*Config, error) {
+	content, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(content)
+}
@@ -0,0 +1,123 @@
+// Package daemon scans a list of repositories on a schedule, and exposes the results as Prometheus metrics.
+package daemon
+
+import (
+	"context"
+	"github.com/binxio/fromage/repository"
+	"github.com/binxio/fromage/scan"
+	"github.com/binxio/fromage/tag"
+	"log"
+	"net/http"
+	"time"
+)
+
+// Options configures the daemon. Scan configures the check of every repository like fromage check, except
+// for the branches which are taken from the configuration.
+type Options struct {
+	Interval time.Duration
+	Scan     scan.Options
+	// CacheDir, if set, holds the mirrors of the repositories, which are updated incrementally.
+	CacheDir string
+	Verbose  bool
+}
+
+// Daemon scans the repositories of the configuration every interval.
+type Daemon struct {
+	config  *Config
+	options Options
+	metrics *Metrics
+}
+
+// New returns a daemon which scans the repositories of the configuration.
+func New(config *Config, options Options) *Daemon {
+	return &Daemon{config: config, options: options, metrics: NewMetrics()}
+}
+
+// Metrics returns the metrics of the scans.
+func (d *Daemon) Metrics() *Metrics {
+	return d.metrics
+}
+
+// ScanAll scans all repositories once. The registries are consulted again for every round.
+func (d *Daemon) ScanAll(ctx context.Context) {
+	tag.ClearCaches()
+	for _, r := range d.config.Repositories {
+		if ctx.Err() != nil {
+			return
+		}
+		d.scan(ctx, r)
+	}
+}
+
+func (d *Daemon) scan(ctx context.Context, config Repository) {
+	start := time.Now()
+	if d.options.Verbose {
+		log.Printf("INFO: scanning %s", config.Url)
+	}
+
+	r, err := repository.OpenReadOnly(ctx, config.Url, d.options.CacheDir, d.options.Verbose)
+	if err == nil {
+		err = r.CheckBranches(config.Branches)
+	}
+	var references scan.DockerfileFromReferences
+	if err == nil {
+		options := d.options.Scan
+		options.Branches = config.Branches
+		references, err = scan.ListReferences(ctx, r, options)
+	}
+	if err != nil {
+		log.Printf("WARNING: failed to scan %s, %s", config.Url, err)
+		d.metrics.RecordError(config.Url, time.Since(start))
+		return
+	}
+
+	outdated := references.Check(d.options.Scan)
+	d.metrics.Record(config.Url, references, outdated, time.Since(start))
+	log.Printf("INFO: %d of %d container image references in %s are out of date", len(outdated),
+		len(references), config.Url)
+}
+
+// Run scans all repositories immediately, and then every interval until the context is done.
+func (d *Daemon) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.options.Interval)
+	defer ticker.Stop()
+	for {
+		d.ScanAll(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ServeHTTP serves the metrics on /metrics.
+func (d *Daemon) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/metrics":
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		if err := d.metrics.Write(w); err != nil {
+			log.Printf("WARNING: failed to write metrics, %s", err)
+		}
+	case "/healthz":
+		w.WriteHeader(http.StatusOK)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// ListenAndServe serves the metrics on the address and scans the repositories, until the context is done.
+func (d *Daemon) ListenAndServe(ctx context.Context, address string) error {
+	server := &http.Server{Addr: address, Handler: d}
+	go d.Run(ctx)
+	go func() {
+		<-ctx.Done()
+		_ = server.Shutdown(context.Background())
+	}()
+
+	log.Printf("INFO: serving metrics on %s", address)
+	if err := server.ListenAndServe(); err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
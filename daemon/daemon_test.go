@@ -0,0 +1,117 @@
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDaemon(t *testing.T) {
+	registryServer := httptest.NewServer(registry.New(registry.Logger(log.New(ioutil.Discard, "", 0))))
+	defer registryServer.Close()
+	u, _ := url.Parse(registryServer.URL)
+	for _, version := range []string{"3.16", "3.17", "3.18"} {
+		image, err := random.Image(64, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		reference, _ := name.ParseReference(fmt.Sprintf("%s/library/alpine:%s", u.Host, version))
+		if err = remote.Write(reference, image); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	dir, err := ioutil.TempDir("", "fromage-daemon")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	source := filepath.Join(dir, "source")
+	g, err := git.PlainInit(source, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content := fmt.Sprintf("FROM %s/library/alpine:3.16\nFROM %s/library/missing:1.0\n", u.Host, u.Host)
+	if err = ioutil.WriteFile(filepath.Join(source, "Dockerfile"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	worktree, _ := g.Worktree()
+	if _, err = worktree.Add("Dockerfile"); err != nil {
+		t.Fatal(err)
+	}
+	_, err = worktree.Commit("add Dockerfile", &git.CommitOptions{
+		Author: &object.Signature{Name: "test", Email: "test@example.com", When: time.Now()},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config, err := Parse([]byte(fmt.Sprintf("repositories:\n  - url: %s\n    branches: [master]\n  - url: %s\n",
+		source, filepath.Join(dir, "missing"))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := New(config, Options{Interval: time.Hour})
+	d.ScanAll(context.Background())
+
+	server := httptest.NewServer(d)
+	defer server.Close()
+	response, err := http.Get(server.URL + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer response.Body.Close()
+	body, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	metrics := string(body)
+
+	image := fmt.Sprintf("%s/library/alpine:3.16", u.Host)
+	labels := fmt.Sprintf(`repo="%s",branch="master",path="Dockerfile",image="%s"`, source, image)
+	for _, expect := range []string{
+		"# TYPE fromage_outdated_references gauge",
+		fmt.Sprintf("fromage_outdated_references{%s} 1", labels),
+		fmt.Sprintf("fromage_reference_versions_behind{%s} 2", labels),
+		fmt.Sprintf(`fromage_registry_errors_total{repo="%s"} 1`, source),
+		fmt.Sprintf(`fromage_scans_total{repo="%s"} 1`, source),
+		fmt.Sprintf(`fromage_scan_errors_total{repo="%s"} 0`, source),
+		fmt.Sprintf(`fromage_scan_errors_total{repo="%s"} 1`, filepath.Join(dir, "missing")),
+	} {
+		if !strings.Contains(metrics, expect+"\n") {
+			t.Fatalf("expected metrics to contain %s, got:\n%s", expect, metrics)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	var tests = []struct {
+		content string
+		err     bool
+	}{
+		{"repositories:\n  - url: https://github.com/binxio/kritis\n", false},
+		{"repositories: []\n", true},
+		{"repositories:\n  - branches: [main]\n", true},
+		{"repositories: {", true},
+	}
+	for _, test := range tests {
+		if _, err := Parse([]byte(test.content)); (err != nil) != test.err {
+			t.Fatalf("expected error %v for %q, got %v", test.err, test.content, err)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package daemon
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/binxio/fromage/scan"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// scanMetrics are the metrics of the latest scan of a repository, and the counters of all its scans.
+type scanMetrics struct {
+	references     scan.DockerfileFromReferences
+	outdated       scan.DockerfileFromReferences
+	duration       time.Duration
+	finished       time.Time
+	scans          int
+	scanErrors     int
+	registryErrors int
+}
+
+// Metrics are the results of the scans of the repositories, exposed in the Prometheus text format.
+type Metrics struct {
+	mutex        sync.Mutex
+	repositories map[string]*scanMetrics
+}
+
+// NewMetrics returns metrics without any scans.
+func NewMetrics() *Metrics {
+	return &Metrics{repositories: make(map[string]*scanMetrics)}
+}
+
+func (m *Metrics) repository(url string) *scanMetrics {
+	result, ok := m.repositories[url]
+	if !ok {
+		result = &scanMetrics{}
+		m.repositories[url] = result
+	}
+	return result
+}
+
+// Record registers the references found in the repository, of which outdated fail the check. The references
+// of the previous scan of the repository are replaced.
+func (m *Metrics) Record(url string, references, outdated scan.DockerfileFromReferences, duration time.Duration) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	r := m.repository(url)
+	r.references, r.outdated = references, outdated
+	r.duration, r.finished = duration, time.Now()
+	r.scans++
+	for _, reference := range references {
+		if reference.RegistryError != "" {
+			r.registryErrors++
+		}
+	}
+}
+
+// RecordError registers a failed scan of the repository. The references of the previous scan are kept.
+func (m *Metrics) RecordError(url string, duration time.Duration) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	r := m.repository(url)
+	r.duration, r.finished = duration, time.Now()
+	r.scans++
+	r.scanErrors++
+}
+
+// escape escapes the label value for the Prometheus text format.
+func escape(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
+
+func referenceLabels(url string, reference *scan.DockerfileFromReference) string {
+	return fmt.Sprintf(`repo="%s",branch="%s",path="%s",image="%s"`, escape(url), escape(reference.Branch),
+		escape(reference.Path), escape(reference.Reference))
+}
+
+// Write writes the metrics in the Prometheus text format, ordered by repository.
+func (m *Metrics) Write(out io.Writer) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	urls := make([]string, 0, len(m.repositories))
+	for url := range m.repositories {
+		urls = append(urls, url)
+	}
+	sort.Strings(urls)
+
+	w := bufio.NewWriter(out)
+	metric := func(name, kind, help string, samples func(url string, r *scanMetrics)) {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+		for _, url := range urls {
+			samples(url, m.repositories[url])
+		}
+	}
+
+	metric("fromage_outdated_references", "gauge", "Container image references which fail the check.",
+		func(url string, r *scanMetrics) {
+			for _, reference := range r.outdated {
+				fmt.Fprintf(w, "fromage_outdated_references{%s} 1\n", referenceLabels(url, reference))
+			}
+		})
+	metric("fromage_reference_versions_behind", "gauge", "Number of newer versions of the container image reference.",
+		func(url string, r *scanMetrics) {
+			for _, reference := range r.references {
+				fmt.Fprintf(w, "fromage_reference_versions_behind{%s} %d\n", referenceLabels(url, reference),
+					len(reference.Newer))
+			}
+		})
+	metric("fromage_scan_duration_seconds", "gauge", "Duration of the latest scan of the repository.",
+		func(url string, r *scanMetrics) {
+			fmt.Fprintf(w, "fromage_scan_duration_seconds{repo=\"%s\"} %g\n", escape(url), r.duration.Seconds())
+		})
+	metric("fromage_last_scan_timestamp_seconds", "gauge", "Time of the latest scan of the repository.",
+		func(url string, r *scanMetrics) {
+			fmt.Fprintf(w, "fromage_last_scan_timestamp_seconds{repo=\"%s\"} %d\n", escape(url), r.finished.Unix())
+		})
+	metric("fromage_scans_total", "counter", "Number of scans of the repository.",
+		func(url string, r *scanMetrics) {
+			fmt.Fprintf(w, "fromage_scans_total{repo=\"%s\"} %d\n", escape(url), r.scans)
+		})
+	metric("fromage_scan_errors_total", "counter", "Number of failed scans of the repository.",
+		func(url string, r *scanMetrics) {
+			fmt.Fprintf(w, "fromage_scan_errors_total{repo=\"%s\"} %d\n", escape(url), r.scanErrors)
+		})
+	metric("fromage_registry_errors_total", "counter", "Number of references of which the registry could not be queried.",
+		func(url string, r *scanMetrics) {
+			fmt.Fprintf(w, "fromage_registry_errors_total{repo=\"%s\"} %d\n", escape(url), r.registryErrors)
+		})
+	return w.Flush()
+}
@@ -0,0 +1,29 @@
+package deprecation
+
+// builtin are the rules for well-known deprecated images.
+const builtin = `
+deprecations:
+  - image: openjdk
+    replacement: eclipse-temurin
+    reason: the openjdk images are deprecated in favour of eclipse-temurin
+  - image: adoptopenjdk
+    replacement: eclipse-temurin
+    reason: the adoptopenjdk images are deprecated in favour of eclipse-temurin
+  - image: gcr.io/google-containers
+    replacement: registry.k8s.io
+    reason: the Kubernetes images moved to registry.k8s.io
+  - image: k8s.gcr.io
+    replacement: registry.k8s.io
+    reason: the Kubernetes images moved to registry.k8s.io
+  - image: node:*-stretch
+    replacement: node:*-bullseye
+    reason: Debian stretch is no longer maintained
+  - image: python:*-stretch
+    replacement: python:*-bullseye
+    reason: Debian stretch is no longer maintained
+  - image: golang:*-stretch
+    replacement: golang:*-bullseye
+    reason: Debian stretch is no longer maintained
+  - image: centos
+    reason: CentOS Linux is discontinued
+`
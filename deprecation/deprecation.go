@@ -0,0 +1,173 @@
+// Package deprecation matches container image references against rules for deprecated image repositories
+// and tags, and determines the recommended replacement.
+package deprecation
+
+import (
+	"fmt"
+	"github.com/google/go-containerregistry/pkg/name"
+	"gopkg.in/yaml.v3"
+	"io/ioutil"
+	"strings"
+)
+
+// Rule marks an image repository, or the tags of a repository matching a pattern, as deprecated. The
+// image is a registry or repository like openjdk, which also matches nested repositories like
+// gcr.io/google-containers/pause for gcr.io/google-containers, optionally followed by a tag pattern with a
+// single wildcard, like node:*-stretch.
+// The replacement has the same form, and the wildcard in its tag is substituted by the matched part of the tag.
+type Rule struct {
+	Image       string `yaml:"image" json:"image"`
+	Replacement string `yaml:"replacement,omitempty" json:"replacement,omitempty"`
+	Reason      string `yaml:"reason,omitempty" json:"reason,omitempty"`
+
+	base        string
+	tag         string
+	replacement string
+	replaceTag  string
+}
+
+// Rules are the deprecation rules, of which the first matching rule applies.
+type Rules []*Rule
+
+type rulesFile struct {
+	Deprecations Rules `yaml:"deprecations"`
+}
+
+// splitImage splits the image into repository and tag pattern.
+func splitImage(image string) (string, string) {
+	if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		return image[:i], image[i+1:]
+	}
+	return image, ""
+}
+
+// normalize returns the fully qualified name of the repository, or of the registry if only a registry
+// like k8s.gcr.io is specified.
+func normalize(repository string) (string, error) {
+	if !strings.Contains(repository, "/") && strings.ContainsAny(repository, ".:") {
+		registry, err := name.NewRegistry(repository)
+		if err != nil {
+			return "", err
+		}
+		return registry.Name(), nil
+	}
+	r, err := name.NewRepository(repository)
+	if err != nil {
+		return "", err
+	}
+	return r.Name(), nil
+}
+
+func (r *Rule) compile() error {
+	repository, tag := splitImage(r.Image)
+	var err error
+	if r.base, err = normalize(repository); err != nil {
+		return fmt.Errorf("%s is not a valid image repository, %s", r.Image, err)
+	}
+	if strings.Count(tag, "*") > 1 {
+		return fmt.Errorf("%s may contain a single wildcard in the tag", r.Image)
+	}
+	r.tag = tag
+
+	r.replacement, r.replaceTag = splitImage(r.Replacement)
+	if r.replacement != "" {
+		if _, err = normalize(r.replacement); err != nil {
+			return fmt.Errorf("%s is not a valid image repository, %s", r.Replacement, err)
+		}
+	}
+	return nil
+}
+
+// Parse reads the rules from the YAML content, in the form:
+//
+//	deprecations:
+//	  - image: openjdk
+//	    replacement: eclipse-temurin
+//	    reason: the openjdk images are deprecated
+func Parse(content []byte) (Rules, error) {
+	var file rulesFile
+	if err := yaml.Unmarshal(content, &file); err != nil {
+		return nil, fmt.Errorf("could not read deprecation rules, %s", err)
+	}
+	for _, rule := range file.Deprecations {
+		if err := rule.compile(); err != nil {
+			return nil, err
+		}
+	}
+	return file.Deprecations, nil
+}
+
+// Load reads the rules from the file.
+func Load(filename string) (Rules, error) {
+	content, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(content)
+}
+
+// Builtin returns the built-in rules for well-known deprecated images.
+func Builtin() Rules {
+	result, err := Parse([]byte(builtin))
+	if err != nil {
+		panic(err)
+	}
+	return result
+}
+
+// match returns the part of s matched by the wildcard in the pattern.
+func match(pattern, s string) (string, bool) {
+	i := strings.Index(pattern, "*")
+	if i < 0 {
+		return "", pattern == s
+	}
+	prefix, suffix := pattern[:i], pattern[i+1:]
+	if len(s) < len(prefix)+len(suffix) || !strings.HasPrefix(s, prefix) || !strings.HasSuffix(s, suffix) {
+		return "", false
+	}
+	return s[len(prefix) : len(s)-len(suffix)], true
+}
+
+// Match returns whether the reference is deprecated by the rule, and its replacement. The replacement is
+// empty if the rule does not specify one.
+func (r *Rule) Match(reference name.Reference) (string, bool) {
+	repository := reference.Context().Name()
+	base := r.base
+	if repository != base && !strings.HasPrefix(repository, base+"/") {
+		return "", false
+	}
+
+	separator, identifier := ":", reference.Identifier()
+	if _, ok := reference.(name.Digest); ok {
+		separator = "@"
+	}
+	wildcard := ""
+	if r.tag != "" {
+		t, ok := reference.(name.Tag)
+		if !ok {
+			return "", false
+		}
+		if wildcard, ok = match(r.tag, t.TagStr()); !ok {
+			return "", false
+		}
+	}
+
+	if r.replacement == "" {
+		return "", true
+	}
+	replacement := r.replacement + repository[len(base):]
+	if r.replaceTag != "" {
+		separator, identifier = ":", strings.Replace(r.replaceTag, "*", wildcard, 1)
+	}
+	return replacement + separator + identifier, true
+}
+
+// Find returns the first rule deprecating the reference, and its replacement.
+func (l Rules) Find(reference name.Reference) (*Rule, string, bool) {
+	for _, rule := range l {
+		if replacement, ok := rule.Match(reference); ok {
+			return rule, replacement, true
+		}
+	}
+	return nil, "", false
+}
@@ -0,0 +1,63 @@
+package deprecation
+
+import (
+	"github.com/google/go-containerregistry/pkg/name"
+	"testing"
+)
+
+func TestFind(t *testing.T) {
+	rules := Builtin()
+	var tests = []struct {
+		reference   string
+		deprecated  bool
+		replacement string
+	}{
+		{"openjdk:11-jre", true, "eclipse-temurin:11-jre"},
+		{"docker.io/library/openjdk:17", true, "eclipse-temurin:17"},
+		{"gcr.io/google-containers/pause:3.1", true, "registry.k8s.io/pause:3.1"},
+		{"k8s.gcr.io/kube-proxy:v1.23.1", true, "registry.k8s.io/kube-proxy:v1.23.1"},
+		{"node:14-stretch", true, "node:14-bullseye"},
+		{"node:14-stretch-slim", false, ""},
+		{"node:14-bullseye", false, ""},
+		{"centos:7", true, ""},
+		{"gcr.io/google-containers-extra/pause:3.1", false, ""},
+		{"eclipse-temurin:17", false, ""},
+		{"openjdk@sha256:3ab1b26d7a1aad6b6d8a7e8d1e5b7b7e1a9f0a1c2e1c0d7e0f8e9a0b1c2d3e4f", true,
+			"eclipse-temurin@sha256:3ab1b26d7a1aad6b6d8a7e8d1e5b7b7e1a9f0a1c2e1c0d7e0f8e9a0b1c2d3e4f"},
+	}
+	for _, test := range tests {
+		reference, err := name.ParseReference(test.reference)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, replacement, ok := rules.Find(reference)
+		if ok != test.deprecated || replacement != test.replacement {
+			t.Fatalf("expected %s deprecated %v with replacement %q, got %v %q", test.reference,
+				test.deprecated, test.replacement, ok, replacement)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	rules, err := Parse([]byte(`
+deprecations:
+  - image: harbor.corp/base/java:*-jdk8
+    replacement: harbor.corp/base/temurin:*-jdk17
+    reason: Java 8 is no longer supported
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	reference, _ := name.ParseReference("harbor.corp/base/java:2023-jdk8")
+	rule, replacement, ok := rules.Find(reference)
+	if !ok || replacement != "harbor.corp/base/temurin:2023-jdk17" || rule.Reason != "Java 8 is no longer supported" {
+		t.Fatalf("expected harbor.corp/base/temurin:2023-jdk17, got %q", replacement)
+	}
+
+	if _, err = Parse([]byte("deprecations:\n  - image: node:*-*\n")); err == nil {
+		t.Fatalf("expected an error for multiple wildcards")
+	}
+	if _, err = Parse([]byte("deprecations:\n  - image: Node\n")); err == nil {
+		t.Fatalf("expected an error for an invalid repository")
+	}
+}
@@ -1,4 +1,4 @@
-package main
+package dockerfile
 
 import (
 	"github.com/binxio/fromage/tag"
@@ -41,13 +41,13 @@ func (b *Bumper) DetermineBumpOrder() {
 	}
 }
 
-func MakeBumper(references []name.Reference, pin *tag.Level, latest bool) Bumper {
+func MakeBumper(references []name.Reference, pin *tag.Level, variantPin *tag.Level, latest bool, platforms map[string]tag.Platforms) Bumper {
 	var result = Bumper{make(map[string]string, len(references)),
 		make([]string, 0, len(references)), false}
 
 	for _, r := range references {
 		if tagRef, ok := r.(name.Tag); ok {
-			if nextTag, err := tag.GetNextVersion(tagRef, pin, latest); err == nil {
+			if nextTag, err := tag.GetNextVersion(tagRef, pin, variantPin, latest, platforms[r.String()]); err == nil {
 				result.bumpReferences[r.String()] = nextTag.String()
 			} else {
 				// skip references which do not have a next version
@@ -0,0 +1,124 @@
+package dockerfile
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Change records the update of a single container image reference in a Dockerfile.
+type Change struct {
+	Branch string `json:"branch,omitempty" yaml:"branch,omitempty"`
+	Path   string `json:"path"`
+	Line   int    `json:"line"`
+	From   string `json:"from"`
+	To     string `json:"to"`
+	Reason string `json:"reason,omitempty" yaml:"reason,omitempty"`
+}
+
+type Changes []*Change
+
+// WithReason sets the reason of all changes.
+func (c Changes) WithReason(reason string) Changes {
+	for _, change := range c {
+		change.Reason = reason
+	}
+	return c
+}
+
+// WithBranch sets the branch of all changes.
+func (c Changes) WithBranch(branch string) Changes {
+	for _, change := range c {
+		change.Branch = branch
+	}
+	return c
+}
+
+// WriteJSON writes the changes as a JSON array.
+func (c Changes) WriteJSON(out io.Writer) error {
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(c)
+}
+
+const diffContext = 3
+
+func splitLines(content []byte) []string {
+	if len(content) == 0 {
+		return []string{}
+	}
+	lines := strings.SplitAfter(string(content), "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+func writeDiffLine(out io.Writer, prefix string, line string) {
+	fmt.Fprintf(out, "%s%s", prefix, line)
+	if !strings.HasSuffix(line, "\n") {
+		fmt.Fprintf(out, "\n\\ No newline at end of file\n")
+	}
+}
+
+// WriteUnifiedDiff writes the difference between the original and the updated content of the Dockerfile as a
+// unified diff, which can be applied with `git apply`. As the updates only replace references, the original
+// and updated content are expected to have the same number of lines; if not, the whole file is replaced.
+func WriteUnifiedDiff(out io.Writer, filename string, original, updated []byte) {
+	before := splitLines(original)
+	after := splitLines(updated)
+
+	if string(original) == string(updated) {
+		return
+	}
+
+	fmt.Fprintf(out, "diff --git a/%s b/%s\n", filename, filename)
+	fmt.Fprintf(out, "--- a/%s\n+++ b/%s\n", filename, filename)
+
+	if len(before) != len(after) {
+		fmt.Fprintf(out, "@@ -1,%d +1,%d @@\n", len(before), len(after))
+		for _, line := range before {
+			writeDiffLine(out, "-", line)
+		}
+		for _, line := range after {
+			writeDiffLine(out, "+", line)
+		}
+		return
+	}
+
+	changed := make([]int, 0)
+	for i := range before {
+		if before[i] != after[i] {
+			changed = append(changed, i)
+		}
+	}
+
+	for h := 0; h < len(changed); {
+		start := changed[h] - diffContext
+		if start < 0 {
+			start = 0
+		}
+
+		// extend the hunk with all changes whose context overlaps
+		last := h
+		for last+1 < len(changed) && changed[last+1]-changed[last] <= 2*diffContext {
+			last++
+		}
+		end := changed[last] + diffContext + 1
+		if end > len(before) {
+			end = len(before)
+		}
+
+		fmt.Fprintf(out, "@@ -%d,%d +%d,%d @@\n", start+1, end-start, start+1, end-start)
+		for i := start; i < end; i++ {
+			if before[i] == after[i] {
+				writeDiffLine(out, " ", before[i])
+			} else {
+				writeDiffLine(out, "-", before[i])
+				writeDiffLine(out, "+", after[i])
+			}
+		}
+		h = last + 1
+	}
+}
@@ -0,0 +1,91 @@
+package dockerfile
+
+import (
+	"bytes"
+	"github.com/google/go-containerregistry/pkg/name"
+	"testing"
+)
+
+func TestUpdateFromStatementsChanges(t *testing.T) {
+	dockerfile := []byte(`FROM golang:1.12 as builder
+
+FROM builder as runtime
+FROM golang:1.12
+`)
+	from, _ := name.ParseReference("golang:1.12")
+	to, _ := name.ParseReference("golang:1.13")
+
+	_, changes, err := UpdateFromStatements(dockerfile, from, to, "Dockerfile", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 2 {
+		t.Fatalf("expected 2 changes, got %d", len(changes))
+	}
+	for i, line := range []int{1, 4} {
+		if changes[i].Line != line || changes[i].From != "golang:1.12" || changes[i].To != "golang:1.13" {
+			t.Fatalf("expected golang:1.12 -> golang:1.13 on line %d, got %v", line, *changes[i])
+		}
+	}
+}
+
+func TestWriteUnifiedDiff(t *testing.T) {
+	var tests = []struct {
+		name     string
+		original string
+		updated  string
+		diff     string
+	}{
+		{
+			"unchanged",
+			"FROM golang:1.12\n",
+			"FROM golang:1.12\n",
+			"",
+		},
+		{
+			"single line",
+			"FROM golang:1.12\nRUN go build\n",
+			"FROM golang:1.13\nRUN go build\n",
+			`diff --git a/Dockerfile b/Dockerfile
+--- a/Dockerfile
++++ b/Dockerfile
+@@ -1,2 +1,2 @@
+-FROM golang:1.12
++FROM golang:1.13
+ RUN go build
+`,
+		},
+		{
+			"separate hunks",
+			"FROM golang:1.12\n1\n2\n3\n4\n5\n6\n7\nFROM alpine:3.16",
+			"FROM golang:1.13\n1\n2\n3\n4\n5\n6\n7\nFROM alpine:3.17",
+			`diff --git a/Dockerfile b/Dockerfile
+--- a/Dockerfile
++++ b/Dockerfile
+@@ -1,4 +1,4 @@
+-FROM golang:1.12
++FROM golang:1.13
+ 1
+ 2
+ 3
+@@ -6,4 +6,4 @@
+ 5
+ 6
+ 7
+-FROM alpine:3.16
+\ No newline at end of file
++FROM alpine:3.17
+\ No newline at end of file
+`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var out bytes.Buffer
+			WriteUnifiedDiff(&out, "Dockerfile", []byte(test.original), []byte(test.updated))
+			if out.String() != test.diff {
+				t.Fatalf("expected diff\n%s\ngot:\n%s", test.diff, out.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package dockerfile
+
+import (
+	"fmt"
+	"github.com/binxio/fromage/tag"
+	"github.com/google/go-containerregistry/pkg/name"
+	"log"
+)
+
+// PinFloatingReferences replaces all references without a tag, or with a floating tag like latest, with the
+// versioned tag which currently refers to the same image. References which cannot be resolved are left as is.
+func PinFloatingReferences(content []byte, filename string, verbose bool) ([]byte, Changes, error) {
+	result := make(Changes, 0)
+	for _, refString := range ExtractFromStatements(content) {
+		ref, err := name.ParseReference(refString)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to parse %s in %s into a reference, %v", refString, filename, err)
+		}
+		floating, ok := ref.(name.Tag)
+		if !ok || !tag.IsFloating(ref) {
+			continue
+		}
+
+		resolved, err := tag.ResolveFloating(floating)
+		if err != nil {
+			log.Printf("WARNING: %s", err)
+			continue
+		}
+
+		c, changes, err := UpdateFromStatements(content, ref, resolved, filename, verbose)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(changes) > 0 {
+			content = c
+			reason := fmt.Sprintf("pinned floating tag %s to %s", floating.TagStr(), resolved.TagStr())
+			result = append(result, changes.WithReason(reason)...)
+		}
+	}
+	return content, result, nil
+}
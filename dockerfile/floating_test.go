@@ -0,0 +1,47 @@
+package dockerfile
+
+import (
+	"fmt"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"io/ioutil"
+	"log"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestPinFloatingReferences(t *testing.T) {
+	server := httptest.NewServer(registry.New(registry.Logger(log.New(ioutil.Discard, "", 0))))
+	defer server.Close()
+	u, _ := url.Parse(server.URL)
+	repository := fmt.Sprintf("%s/library/node", u.Host)
+
+	for _, tags := range [][]string{{"18.19.0", "18"}, {"20.11.0", "20", "latest"}, {"20.11.0-alpine", "lts-alpine", "alpine"}, {"edge"}} {
+		image, err := random.Image(64, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, tag := range tags {
+			reference, _ := name.ParseReference(repository + ":" + tag)
+			if err = remote.Write(reference, image); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	content := []byte(fmt.Sprintf("FROM %[1]s AS build\nFROM %[1]s:lts-alpine\nFROM %[1]s:alpine\nFROM %[1]s:edge\nFROM %[1]s:18\n", repository))
+	result, changes, err := PinFloatingReferences(content, "Dockerfile", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := fmt.Sprintf("FROM %[1]s:20.11.0 AS build\nFROM %[1]s:20.11.0-alpine\nFROM %[1]s:alpine\nFROM %[1]s:edge\nFROM %[1]s:18\n", repository)
+	if string(result) != expect {
+		t.Fatalf("expected\n%s\ngot\n%s", expect, result)
+	}
+	if len(changes) != 2 || changes[0].Reason != "pinned floating tag latest to 20.11.0" || changes[1].Line != 2 {
+		t.Fatalf("expected 2 changes, got %v", changes)
+	}
+}
@@ -0,0 +1,282 @@
+// Package dockerfile extracts and updates the container image references in the FROM statements of a Dockerfile.
+package dockerfile
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/binxio/fromage/tag"
+	"github.com/google/go-containerregistry/pkg/name"
+	"log"
+	"regexp"
+	"strings"
+)
+
+var (
+	fromRegExp      = regexp.MustCompile(`(?m)^\s*[Ff][Rr][Oo][Mm]\s+(--[Pp][Ll][Aa][Tt][Ff][Oo][Rr][Mm]=(?P<platform>[^\s]+)\s+)?(?P<reference>[^\s]+)(\s*[Aa][Ss]\s+(?P<alias>[^\s]+))?.*$`)
+	fromRegExpNames = fromRegExp.SubexpNames()
+)
+
+// FromStatement is a FROM statement in a Dockerfile. Line and Column are the 1-based position
+// of the reference, Stage is the 0-based index of the build stage it starts. Final indicates that
+// the reference is the base of the final build stage, directly or through the aliases of other stages.
+// Builder indicates that the reference is only used in builder stages.
+type FromStatement struct {
+	Reference string
+	Platform  string
+	Alias     string
+	Line      int
+	Column    int
+	Stage     int
+	Final     bool
+	Builder   bool
+}
+
+// ParseFromStatements returns all FROM statements in the content, in order of appearance.
+func ParseFromStatements(content []byte) []FromStatement {
+	result := make([]FromStatement, 0)
+
+	for _, match := range fromRegExp.FindAllSubmatchIndex(content, -1) {
+		statement := FromStatement{}
+		for i, name := range fromRegExpNames {
+			start, end := match[i*2], match[i*2+1]
+			if start < 0 {
+				continue
+			}
+			switch name {
+			case "reference":
+				lineStart := bytes.LastIndexByte(content[:start], '\n') + 1
+				statement.Reference = string(content[start:end])
+				statement.Line = bytes.Count(content[:start], []byte("\n")) + 1
+				statement.Column = start - lineStart + 1
+			case "platform":
+				statement.Platform = string(content[start:end])
+			case "alias":
+				statement.Alias = string(content[start:end])
+			default:
+				// ignore
+			}
+		}
+		statement.Stage = len(result)
+		result = append(result, statement)
+	}
+	return result
+}
+
+// ExtractFromReferences returns the first FROM statement of every container image reference in the content. References
+// to the alias of a previous build stage are skipped.
+func ExtractFromReferences(content []byte) []FromStatement {
+	result := make([]FromStatement, 0)
+	aliases := make(map[string]string, 0)
+	references := make(map[string]bool, 0)
+
+	statements := ParseFromStatements(content)
+	stages := ParseStages(content)
+	for _, statement := range statements {
+		statement.Final, statement.Builder = stageUse(stages, statement.Reference)
+		if statement.Alias != "" {
+			// register the reference as an alias
+			aliases[statement.Alias] = statement.Reference
+		}
+		if _, ok := aliases[statement.Reference]; !ok {
+			// the reference is not pointing to an alias
+			if _, ok := references[statement.Reference]; !ok {
+				// the reference was not yet registered
+				references[statement.Reference] = true
+				result = append(result, statement)
+			}
+		}
+	}
+	return result
+}
+
+func ExtractFromStatements(content []byte) []string {
+	statements := ExtractFromReferences(content)
+	result := make([]string, 0, len(statements))
+	for _, statement := range statements {
+		result = append(result, statement.Reference)
+	}
+	return result
+}
+
+// ExtractFromPlatforms returns the platforms specified with the --platform flag on the FROM statements,
+// by reference. Platforms specified as a build argument, like $BUILDPLATFORM, are ignored.
+func ExtractFromPlatforms(content []byte) map[string]tag.Platforms {
+	result := make(map[string]tag.Platforms, 0)
+
+	for _, statement := range ParseFromStatements(content) {
+		if statement.Platform == "" || strings.Contains(statement.Platform, "$") {
+			continue
+		}
+		platforms, err := tag.ParsePlatforms(statement.Platform)
+		if err != nil {
+			log.Printf("WARNING: ignoring platform of %s, %s", statement.Reference, err)
+			continue
+		}
+		result[statement.Reference] = result[statement.Reference].Merge(platforms)
+	}
+	return result
+}
+
+// UpdateFromStatements replaces all occurrences of the reference `from` with `to`, and returns the updated content
+// together with the changes made.
+func UpdateFromStatements(content []byte, from name.Reference, to name.Reference, filename string, verbose bool) ([]byte, Changes, error) {
+	previous := 0
+	changes := make(Changes, 0)
+	result := bytes.Buffer{}
+	allMatches := fromRegExp.FindAllSubmatchIndex(content, -1)
+	for _, match := range allMatches {
+		for i, n := range fromRegExpNames {
+			switch n {
+			case "reference":
+				var err error
+				var ref name.Reference
+				var start = match[i*2]
+				var end = match[i*2+1]
+				var s = string(content[start:end])
+				if ref, err = name.ParseReference(s); err != nil {
+					return nil, nil, fmt.Errorf("could not parse %s in %s as container reference, %s",
+						s, filename, err)
+				}
+
+				if ref.Context().Name() == from.Context().Name() {
+					if ref.Identifier() == from.Identifier() {
+
+						changes = append(changes, &Change{
+							Path: filename,
+							Line: bytes.Count(content[:start], []byte("\n")) + 1,
+							From: s,
+							To:   to.String(),
+						})
+						if verbose {
+							log.Printf("INFO: updating reference %s to %s in %s", ref, to, filename)
+						}
+
+						result.Write(content[previous:start])
+						result.Write([]byte(to.String()))
+						previous = end
+					}
+				}
+			default:
+				// ignore
+			}
+		}
+	}
+	if previous < len(content) {
+		result.Write(content[previous:len(content)])
+	}
+
+	return result.Bytes(), changes, nil
+}
+
+// UpdateFromStatementAt replaces the reference of the FROM statement at index, the 0-based position of the
+// statement in the content, with `to`, if it refers to `from`. Other statements which refer to `from` are
+// left as is. It returns the updated content together with the change made, if any.
+func UpdateFromStatementAt(content []byte, index int, from name.Reference, to name.Reference, filename string, verbose bool) ([]byte, Changes, error) {
+	matches := fromRegExp.FindAllSubmatchIndex(content, -1)
+	if index < 0 || index >= len(matches) {
+		return content, Changes{}, nil
+	}
+	for i, n := range fromRegExpNames {
+		if n != "reference" {
+			continue
+		}
+		start, end := matches[index][i*2], matches[index][i*2+1]
+		s := string(content[start:end])
+		ref, err := name.ParseReference(s)
+		if err != nil {
+			return nil, nil, fmt.Errorf("could not parse %s in %s as container reference, %s", s, filename, err)
+		}
+		if ref.Context().Name() != from.Context().Name() || ref.Identifier() != from.Identifier() {
+			return content, Changes{}, nil
+		}
+		line := bytes.Count(content[:start], []byte("\n")) + 1
+		if verbose {
+			log.Printf("INFO: updating reference %s to %s on line %d in %s", ref, to, line, filename)
+		}
+		result := bytes.Buffer{}
+		result.Write(content[:start])
+		result.Write([]byte(to.String()))
+		result.Write(content[end:])
+		return result.Bytes(), Changes{{Path: filename, Line: line, From: s, To: to.String()}}, nil
+	}
+	return content, Changes{}, nil
+}
+
+// UpdateAllFromStatements bumps all references to their next version, and returns the updated content together
+// with the changes made.
+func UpdateAllFromStatements(content []byte, filename string, pin *tag.Level, latest bool, platforms tag.Platforms, verbose bool) ([]byte, Changes, error) {
+	return UpdateSelectedFromStatements(content, filename, pin, nil, latest, platforms, AllStages, verbose)
+}
+
+// StageSelection selects the references to update by the build stages in which they are used.
+type StageSelection int
+
+const (
+	// AllStages selects all references.
+	AllStages StageSelection = iota
+	// OnlyFinalStage selects the reference on which the final stage is based.
+	OnlyFinalStage
+	// SkipBuilderStages selects the references which are not only used in builder stages.
+	SkipBuilderStages
+)
+
+// Selects returns true if the reference of the statement is selected.
+func (s StageSelection) Selects(statement FromStatement) bool {
+	switch s {
+	case OnlyFinalStage:
+		return statement.Final
+	case SkipBuilderStages:
+		return !statement.Builder
+	default:
+		return true
+	}
+}
+
+// UpdateSelectedFromStatements updates the references selected by the build stages in which they are used, like
+// UpdateAllFromStatements. The version of versioned variants, like alpine3.18, is pinned on the level of
+// variantPin, if set.
+func UpdateSelectedFromStatements(content []byte, filename string, pin *tag.Level, variantPin *tag.Level, latest bool, platforms tag.Platforms, selection StageSelection, verbose bool) ([]byte, Changes, error) {
+	result := make(Changes, 0)
+	reason := "next version"
+	if latest {
+		reason = "latest version"
+	}
+	if pin != nil {
+		reason = fmt.Sprintf("%s pinned on %s level", reason, strings.ToLower(pin.String()))
+	}
+	if variantPin != nil {
+		reason = fmt.Sprintf("%s, variant pinned on %s level", reason, strings.ToLower(variantPin.String()))
+	}
+
+	refs := make([]string, 0)
+	for _, statement := range ExtractFromReferences(content) {
+		if selection.Selects(statement) {
+			refs = append(refs, statement.Reference)
+		}
+	}
+	var references = make([]name.Reference, 0, len(refs))
+	var required = ExtractFromPlatforms(content)
+	for _, refString := range refs {
+		ref, err := name.ParseReference(refString)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to parse %s in %s into a reference, %v", refString, filename, err)
+		}
+		references = append(references, ref)
+		required[ref.String()] = platforms.Merge(required[refString])
+	}
+
+	bumper := MakeBumper(references, pin, variantPin, latest, required)
+	for _, r := range bumper.bumpOrder {
+		from, _ := name.ParseReference(r)
+		to, _ := name.ParseReference(bumper.bumpReferences[r])
+		c, changes, err := UpdateFromStatements(content, from, to, filename, true)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(changes) > 0 {
+			content = c
+			result = append(result, changes.WithReason(reason)...)
+		}
+	}
+	return content, result, nil
+}
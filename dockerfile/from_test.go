@@ -0,0 +1,289 @@
+package dockerfile
+
+import (
+	"github.com/binxio/fromage/tag"
+	"github.com/google/go-containerregistry/pkg/name"
+	"testing"
+)
+
+func TestExtractFromStatements(t *testing.T) {
+	dockerfile := []byte(`
+FROM golang:1.12 as builder #comment
+
+FROM builder als runtime
+
+
+`)
+
+	result := ExtractFromStatements(dockerfile)
+	if len(result) != 1 {
+		t.Fatalf("expected 1 hit, got %d", len(result))
+	}
+
+	if result[0] != "golang:1.12" {
+		t.Fatalf("expected golang:1.12, got %s", result[0])
+	}
+}
+
+type updateTest struct {
+	dockerfile    []byte
+	reference     string
+	updated       bool
+	newDockerfile []byte
+}
+
+func TestUpdateFromStatements(t *testing.T) {
+	var tests = []updateTest{
+		{
+			[]byte(`
+FROM golang@sha256:d0e79a9c39cdb3d71cc45fec929d1308d50420b79201467ec602b1b80cc314a8 as builder #comment
+
+FROM builder als runtime
+`),
+			"golang@sha256:d0e79a9c39cdb3d71cc45fec929d1308d50420b79201467ec602b1b80cc314a8",
+			false,
+			[]byte(`
+FROM golang@sha256:d0e79a9c39cdb3d71cc45fec929d1308d50420b79201467ec602b1b80cc314a8 as builder #comment
+
+FROM builder als runtime
+`),
+		},
+		{
+			[]byte(`
+FROM golang:1.12 as builder #comment
+
+FROM builder as runtime
+`),
+			"golang:1.12",
+			true,
+			[]byte(`
+FROM golang:1.13 as builder #comment
+
+FROM builder as runtime
+`),
+		},
+	}
+
+	for _, test := range tests {
+		r, err := name.ParseReference(test.reference)
+		if err != nil {
+			t.Fatal(err)
+		}
+		reference, _ := r.(name.Tag)
+		nextRef, _ := tag.GetNextVersion(reference, nil, nil, false, nil)
+		result, changes, err := UpdateFromStatements(test.dockerfile, reference, nextRef, "./Dockerfile", true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if updated := len(changes) > 0; updated != test.updated {
+			t.Fatalf("expected updated to be %v, in %s", test.updated, string(test.dockerfile))
+		}
+		if string(result) != string(test.newDockerfile) {
+			t.Fatalf("update did not match expected result")
+		}
+	}
+}
+
+type updateAlltest struct {
+	dockerfile    []byte
+	updated       bool
+	newDockerfile []byte
+}
+
+func TestUpdateAllFromStatements(t *testing.T) {
+	var tests = []updateAlltest{
+		{
+			[]byte(`
+FROM golang:1.12.17 as builder #comment
+
+FROM builder as runtime
+
+FROM php:7.2-fpm
+`),
+			true,
+			[]byte(`
+FROM golang:1.13.0 as builder #comment
+
+FROM builder as runtime
+
+FROM php:7.3-fpm
+`),
+		},
+		{
+			[]byte(`
+FROM golang:1.12 as builder #comment
+
+FROM builder as runtime
+
+FROM php:7.2-fpm
+`),
+			true,
+			[]byte(`
+FROM golang:1.13 as builder #comment
+
+FROM builder as runtime
+
+FROM php:7.3-fpm
+`),
+		},
+		{
+			[]byte(`
+FROM golang:1.12.0 as builder #comment
+
+FROM builder as runtime
+
+FROM php:7.2-fpm
+`),
+			true,
+			[]byte(`
+FROM golang:1.12.1 as builder #comment
+
+FROM builder as runtime
+
+FROM php:7.3-fpm
+`),
+		},
+
+		{
+			[]byte(`
+FROM golang:latest as builder #comment
+
+FROM builder as runtime
+
+FROM php:latest
+`),
+			false,
+			[]byte(`
+FROM golang:latest as builder #comment
+
+FROM builder as runtime
+
+FROM php:latest
+`),
+		},
+		{
+			[]byte(`
+# update ordered to start with highest version
+FROM golang:1.12
+
+FROM golang:1.13
+
+FROM golang:1.14
+`),
+			true,
+			[]byte(`
+# update ordered to start with highest version
+FROM golang:1.13
+
+FROM golang:1.14
+
+FROM golang:1.15
+`),
+		},
+	}
+	for _, test := range tests {
+		newDockerfile, changes, err := UpdateAllFromStatements(test.dockerfile, "./Dockerfile", nil, false, nil, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if updated := len(changes) > 0; test.updated != updated {
+			t.Fatalf("expected updated to be %v in %s", test.updated, string(test.dockerfile))
+		}
+
+		if string(test.newDockerfile) != string(newDockerfile) {
+			t.Fatalf("expected new Dockerfile to be\n%s\ngot:\n%s\n",
+				string(test.newDockerfile), string(newDockerfile))
+		}
+	}
+}
+
+func TestUpdateFromStatementAt(t *testing.T) {
+	content := []byte("FROM golang:1.13 AS build\nFROM golang:1.13 AS test\nFROM alpine:3.18\n")
+	from, _ := name.ParseReference("golang:1.13")
+	to, _ := name.ParseReference("golang:1.12")
+
+	result, changes, err := UpdateFromStatementAt(content, 1, from, to, "Dockerfile", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(result) != "FROM golang:1.13 AS build\nFROM golang:1.12 AS test\nFROM alpine:3.18\n" {
+		t.Fatalf("expected only the second FROM statement to change, got\n%s", string(result))
+	}
+	if len(changes) != 1 || changes[0].Line != 2 || changes[0].From != "golang:1.13" || changes[0].To != "golang:1.12" {
+		t.Fatalf("expected a change of golang:1.13 on line 2, got %v", changes)
+	}
+
+	for _, index := range []int{2, 3} {
+		result, changes, err = UpdateFromStatementAt(content, index, from, to, "Dockerfile", false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(result) != string(content) || len(changes) != 0 {
+			t.Fatalf("expected no change of FROM statement %d, got %v", index, changes)
+		}
+	}
+}
+
+func TestExtractFromPlatforms(t *testing.T) {
+	dockerfile := []byte(`
+FROM --platform=$BUILDPLATFORM golang:1.12 as builder
+
+FROM --platform=linux/arm64 alpine:3.16
+FROM --platform=linux/amd64 alpine:3.16
+`)
+
+	result := ExtractFromStatements(dockerfile)
+	if len(result) != 2 || result[0] != "golang:1.12" || result[1] != "alpine:3.16" {
+		t.Fatalf("expected golang:1.12 and alpine:3.16, got %v", result)
+	}
+
+	platforms := ExtractFromPlatforms(dockerfile)
+	if _, ok := platforms["golang:1.12"]; ok {
+		t.Fatalf("expected no platform for golang:1.12, got %s", platforms["golang:1.12"])
+	}
+	if platforms["alpine:3.16"].String() != "linux/arm64,linux/amd64" {
+		t.Fatalf("expected linux/arm64,linux/amd64 for alpine:3.16, got %s", platforms["alpine:3.16"])
+	}
+}
+
+func TestExtractFromReferences(t *testing.T) {
+	dockerfile := []byte(`FROM golang:1.12 as builder
+
+  from --platform=linux/arm64 alpine:3.16
+FROM builder
+FROM golang:1.12
+`)
+
+	result := ExtractFromReferences(dockerfile)
+	expect := []FromStatement{
+		{Reference: "golang:1.12", Alias: "builder", Line: 1, Column: 6, Final: true},
+		{Reference: "alpine:3.16", Platform: "linux/arm64", Line: 3, Column: 31, Stage: 1},
+	}
+	if len(result) != len(expect) {
+		t.Fatalf("expected %d references, got %v", len(expect), result)
+	}
+	for i := range expect {
+		if result[i] != expect[i] {
+			t.Fatalf("expected %v, got %v", expect[i], result[i])
+		}
+	}
+}
+
+func TestExtractFromReferencesFinalStage(t *testing.T) {
+	tests := []struct {
+		dockerfile string
+		final      string
+	}{
+		{"FROM golang:1.12 AS builder\nFROM alpine:3.16\n", "alpine:3.16"},
+		{"FROM golang:1.12 AS builder\nFROM builder AS test\nFROM test\n", "golang:1.12"},
+		{"FROM golang:1.12 AS builder\nFROM scratch\n", "scratch"},
+	}
+	for _, test := range tests {
+		for _, statement := range ExtractFromReferences([]byte(test.dockerfile)) {
+			if statement.Final != (statement.Reference == test.final) {
+				t.Fatalf("expected final stage %s in %q, got %v", test.final, test.dockerfile, statement)
+			}
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package dockerfile
+
+import (
+	"fmt"
+	"github.com/binxio/fromage/deprecation"
+	"github.com/google/go-containerregistry/pkg/name"
+	"strings"
+)
+
+// rewrite returns the new reference for a reference, and the reason for the change. It returns false
+// if the reference should not be changed.
+type rewrite func(ref name.Reference) (to string, reason string, ok bool)
+
+// rewriteImageReferences rewrites all references in the content as determined by the rewrite function.
+// It fails if a rewritten reference does not exist.
+func rewriteImageReferences(content []byte, filename string, verbose bool, rewrite rewrite) ([]byte, Changes, error) {
+	result := make(Changes, 0)
+	refs := ExtractFromStatements(content)
+	for _, refString := range refs {
+		ref, err := name.ParseReference(refString)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		newRefString, reason, ok := rewrite(ref)
+		if !ok {
+			continue
+		}
+		newRef, err := name.ParseReference(newRefString)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if !RepositoryExists(newRef, verbose) {
+			return nil, nil, fmt.Errorf("%s is not a valid image reference", newRef)
+		}
+
+		c, changes, err := UpdateFromStatements(content, ref, newRef, filename, verbose)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(changes) > 0 {
+			content = c
+			result = append(result, changes.WithReason(reason)...)
+		}
+	}
+	return content, result, nil
+}
+
+// MoveImageReferences rewrites all references in the content from the repository context `from` to
+// the repository context `to`. It fails if a moved reference does not exist.
+func MoveImageReferences(content []byte, filename string, verbose bool, from, to string) ([]byte, Changes, error) {
+	reason := fmt.Sprintf("moved from %s to %s", from, to)
+	return rewriteImageReferences(content, filename, verbose, func(ref name.Reference) (string, string, bool) {
+		fullRef := ref.Name()
+
+		if !strings.HasPrefix(fullRef, from) || len(fullRef) <= len(from) {
+			return "", "", false
+		}
+
+		if delimiter := fullRef[len(from)]; delimiter != ':' && delimiter != '/' && delimiter != '@' {
+			return "", "", false
+		}
+
+		if to == "index.docker.io/library" {
+			return fullRef[len(from)+1:], reason, true
+		}
+		return to + fullRef[len(from):], reason, true
+	})
+}
+
+// ReplaceDeprecatedReferences rewrites all references in the content deprecated by the rules to their
+// replacement. References deprecated by a rule without replacement are left as is. It fails if a
+// replacement does not exist.
+func ReplaceDeprecatedReferences(content []byte, filename string, verbose bool, rules deprecation.Rules) ([]byte, Changes, error) {
+	return rewriteImageReferences(content, filename, verbose, func(ref name.Reference) (string, string, bool) {
+		rule, replacement, ok := rules.Find(ref)
+		if !ok || replacement == "" {
+			return "", "", false
+		}
+		reason := fmt.Sprintf("%s is deprecated", rule.Image)
+		if rule.Reason != "" {
+			reason = fmt.Sprintf("%s, %s", reason, rule.Reason)
+		}
+		return replacement, reason, true
+	})
+}
@@ -0,0 +1,136 @@
+package dockerfile
+
+import (
+	"fmt"
+	"github.com/binxio/fromage/deprecation"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"io/ioutil"
+	"log"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+func TestMoveImageReferences(t *testing.T) {
+	type args struct {
+		content  []byte
+		filename string
+		verbose  bool
+		from     string
+		to       string
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    []byte
+		want1   bool
+		wantErr bool
+	}{
+		{
+			"simple",
+			args{[]byte(`FROM python:3.7`),
+				"Dockerfile",
+				true,
+				"index.docker.io/library",
+				"public.ecr.aws/docker/library",
+			},
+			[]byte(`FROM public.ecr.aws/docker/library/python:3.7`),
+			true,
+			false,
+		},
+		{
+			"and back again",
+			args{[]byte(`FROM public.ecr.aws/docker/library/python:3.7`),
+				"Dockerfile",
+				true,
+				"public.ecr.aws/docker/library",
+				"index.docker.io/library",
+			},
+			[]byte(`FROM python:3.7`),
+			true,
+			false,
+		},
+
+		{
+			"error",
+			args{[]byte(`FROM python:3.7`),
+				"Dockerfile",
+				true,
+				"index.docker.io/library",
+				"public.ecr.aws/dorker/library",
+			},
+			nil,
+			false,
+			true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, changes, err := MoveImageReferences(tt.args.content, tt.args.filename, tt.args.verbose, tt.args.from, tt.args.to)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("MoveImageReferences() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("MoveImageReferences() got = %v, want %v", got, tt.want)
+			}
+			if got1 := len(changes) > 0; got1 != tt.want1 {
+				t.Errorf("MoveImageReferences() got1 = %v, want %v", got1, tt.want1)
+			}
+		})
+	}
+}
+
+func TestReplaceDeprecatedReferences(t *testing.T) {
+	server := httptest.NewServer(registry.New(registry.Logger(log.New(ioutil.Discard, "", 0))))
+	defer server.Close()
+	u, _ := url.Parse(server.URL)
+
+	image, err := random.Image(64, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	replacement, _ := name.ParseReference(fmt.Sprintf("%s/base/temurin:11-jre", u.Host))
+	if err = remote.Write(replacement, image); err != nil {
+		t.Fatal(err)
+	}
+
+	rules, err := deprecation.Parse([]byte(fmt.Sprintf(`
+deprecations:
+  - image: %[1]s/base/openjdk
+    replacement: %[1]s/base/temurin
+    reason: use temurin
+  - image: %[1]s/base/centos
+`, u.Host)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	content := []byte(fmt.Sprintf("FROM %[1]s/base/openjdk:11-jre\nFROM %[1]s/base/centos:7\n", u.Host))
+	result, changes, err := ReplaceDeprecatedReferences(content, "Dockerfile", false, rules)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := fmt.Sprintf("FROM %[1]s/base/temurin:11-jre\nFROM %[1]s/base/centos:7\n", u.Host)
+	if string(result) != expect {
+		t.Fatalf("expected\n%s\ngot\n%s", expect, result)
+	}
+	if len(changes) != 1 || changes[0].Reason != fmt.Sprintf("%s/base/openjdk is deprecated, use temurin", u.Host) {
+		t.Fatalf("expected a single change, got %v", changes)
+	}
+
+	if rules, err = deprecation.Parse([]byte(fmt.Sprintf(`
+deprecations:
+  - image: %[1]s/base/centos
+    replacement: %[1]s/base/rockylinux
+`, u.Host))); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err = ReplaceDeprecatedReferences(content, "Dockerfile", false, rules); err == nil {
+		t.Fatalf("expected an error for a non-existing replacement")
+	}
+}
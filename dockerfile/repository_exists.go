@@ -1,4 +1,4 @@
-package main
+package dockerfile
 
 import (
 	"github.com/google/go-containerregistry/pkg/crane"
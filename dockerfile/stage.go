@@ -0,0 +1,145 @@
+package dockerfile
+
+import (
+	"bytes"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var copyFromRegExp = regexp.MustCompile(`(?mi)^[ \t]*COPY[ \t][^\n]*?--from=([^\s]+)`)
+
+// Stage is a build stage of a Dockerfile. Parent is the index of the stage on which it is based, or -1
+// if it is based on an image. CopiesFrom are the indexes of the stages from which files are copied.
+//
+// Runtime indicates that the stage is the final stage, or a stage on which the final stage is based.
+// Reachable indicates that the stage contributes to the final image, either as runtime stage or as a
+// stage from which a reachable stage copies files. Builder indicates that the stage is not a runtime
+// stage, but other stages are based on it or copy files from it.
+type Stage struct {
+	Index      int
+	Name       string
+	Base       string
+	Platform   string
+	Line       int
+	Parent     int
+	CopiesFrom []int
+	Runtime    bool
+	Reachable  bool
+	Builder    bool
+}
+
+// String returns the name of the stage, or its index if it has no name.
+func (s Stage) String() string {
+	if s.Name != "" {
+		return s.Name
+	}
+	return strconv.Itoa(s.Index)
+}
+
+// ParseStages returns the graph of build stages of the Dockerfile content, in order of appearance.
+func ParseStages(content []byte) []Stage {
+	statements := ParseFromStatements(content)
+	result := make([]Stage, 0, len(statements))
+	names := make(map[string]int, len(statements))
+
+	stageOf := func(name string) (int, bool) {
+		if index, ok := names[strings.ToLower(name)]; ok {
+			return index, true
+		}
+		if index, err := strconv.Atoi(name); err == nil && index >= 0 && index < len(result) {
+			return index, true
+		}
+		return -1, false
+	}
+
+	for _, statement := range statements {
+		parent, _ := stageOf(statement.Reference)
+		result = append(result, Stage{
+			Index:    statement.Stage,
+			Name:     statement.Alias,
+			Base:     statement.Reference,
+			Platform: statement.Platform,
+			Line:     statement.Line,
+			Parent:   parent,
+		})
+		if statement.Alias != "" {
+			if _, ok := names[strings.ToLower(statement.Alias)]; !ok {
+				names[strings.ToLower(statement.Alias)] = statement.Stage
+			}
+		}
+	}
+
+	for _, match := range copyFromRegExp.FindAllSubmatchIndex(content, -1) {
+		line := bytes.Count(content[:match[2]], []byte("\n")) + 1
+		current := -1
+		for _, stage := range result {
+			if stage.Line <= line {
+				current = stage.Index
+			}
+		}
+		if current < 0 {
+			continue
+		}
+		// only stages before the current stage can be referenced, names of later stages are images
+		if from, ok := stageOf(string(content[match[2]:match[3]])); ok && from < current {
+			result[current].CopiesFrom = append(result[current].CopiesFrom, from)
+		}
+	}
+
+	if len(result) == 0 {
+		return result
+	}
+	for i := len(result) - 1; i >= 0; i = result[i].Parent {
+		result[i].Runtime = true
+	}
+	markReachable(result, len(result)-1)
+	for _, stage := range result {
+		if stage.Parent >= 0 {
+			result[stage.Parent].Builder = true
+		}
+		for _, from := range stage.CopiesFrom {
+			result[from].Builder = true
+		}
+	}
+	for i := range result {
+		result[i].Builder = result[i].Builder && !result[i].Runtime
+	}
+	return result
+}
+
+func markReachable(stages []Stage, index int) {
+	if index < 0 || stages[index].Reachable {
+		return
+	}
+	stages[index].Reachable = true
+	markReachable(stages, stages[index].Parent)
+	for _, from := range stages[index].CopiesFrom {
+		markReachable(stages, from)
+	}
+}
+
+// stageUse returns whether the image reference is the base of the final stage, and whether it is only used
+// as base of builder stages.
+func stageUse(stages []Stage, reference string) (final bool, builder bool) {
+	builder = true
+	for _, stage := range stages {
+		if stage.Parent >= 0 || stage.Base != reference {
+			continue
+		}
+		final = final || stage.Runtime
+		builder = builder && stage.Builder
+	}
+	return final, builder && !final
+}
+
+// StagesOf returns the stages based on the image reference.
+func StagesOf(stages []Stage, reference string) []Stage {
+	result := make([]Stage, 0)
+	for _, stage := range stages {
+		if stage.Parent < 0 && stage.Base == reference {
+			result = append(result, stage)
+		}
+	}
+	return result
+}
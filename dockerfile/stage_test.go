@@ -0,0 +1,94 @@
+package dockerfile
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseStages(t *testing.T) {
+	content := []byte(`FROM golang:1.21 AS build
+RUN go build -o /app .
+
+FROM node:20 AS assets
+RUN npm run build
+
+FROM build AS test
+RUN go test ./...
+
+FROM alpine:3.19 AS lint
+COPY --from=0 /app /app
+
+FROM gcr.io/distroless/static AS base
+
+FROM base
+COPY --chown=nonroot --from=build /app /app
+COPY --from=assets /dist /dist
+COPY --from=nginx:1.25 /etc/nginx /etc/nginx
+`)
+	stages := ParseStages(content)
+	type expect struct {
+		name       string
+		parent     int
+		copiesFrom []int
+		runtime    bool
+		reachable  bool
+		builder    bool
+	}
+	expected := []expect{
+		{"build", -1, nil, false, true, true},
+		{"assets", -1, nil, false, true, true},
+		{"test", 0, nil, false, false, false},
+		{"lint", -1, []int{0}, false, false, false},
+		{"base", -1, nil, true, true, false},
+		{"5", 4, []int{0, 1}, true, true, false},
+	}
+	if len(stages) != len(expected) {
+		t.Fatalf("expected %d stages, got %v", len(expected), stages)
+	}
+	for i, e := range expected {
+		s := stages[i]
+		if s.String() != e.name || s.Parent != e.parent || !reflect.DeepEqual(s.CopiesFrom, e.copiesFrom) ||
+			s.Runtime != e.runtime || s.Reachable != e.reachable || s.Builder != e.builder {
+			t.Fatalf("expected %v for stage %d, got %+v", e, i, s)
+		}
+	}
+
+	var tests = []struct {
+		reference string
+		final     bool
+		builder   bool
+	}{
+		{"golang:1.21", false, true},
+		{"node:20", false, true},
+		{"alpine:3.19", false, false},
+		{"gcr.io/distroless/static", true, false},
+	}
+	for _, test := range tests {
+		if final, builder := stageUse(stages, test.reference); final != test.final || builder != test.builder {
+			t.Fatalf("expected %s final %v builder %v, got %v %v", test.reference, test.final, test.builder, final, builder)
+		}
+	}
+}
+
+func TestStageSelection(t *testing.T) {
+	content := []byte("FROM golang:1.21 AS build\nFROM alpine:3.19 AS test\nFROM gcr.io/distroless/static\nCOPY --from=build /app /app\n")
+	var tests = []struct {
+		selection StageSelection
+		expect    []string
+	}{
+		{AllStages, []string{"golang:1.21", "alpine:3.19", "gcr.io/distroless/static"}},
+		{OnlyFinalStage, []string{"gcr.io/distroless/static"}},
+		{SkipBuilderStages, []string{"alpine:3.19", "gcr.io/distroless/static"}},
+	}
+	for _, test := range tests {
+		selected := make([]string, 0)
+		for _, statement := range ExtractFromReferences(content) {
+			if test.selection.Selects(statement) {
+				selected = append(selected, statement.Reference)
+			}
+		}
+		if !reflect.DeepEqual(selected, test.expect) {
+			t.Fatalf("expected %v for selection %d, got %v", test.expect, test.selection, selected)
+		}
+	}
+}
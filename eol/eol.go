@@ -0,0 +1,194 @@
+// Package eol determines whether container images are past their end-of-life, using the product release
+// cycles in the shape published by https://endoflife.date.
+package eol
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/binxio/fromage/tag"
+	"github.com/google/go-containerregistry/pkg/name"
+	"io/ioutil"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const dateLayout = "2006-01-02"
+
+// EndOfLife is the end-of-life of a release cycle, which is either a date or a boolean.
+type EndOfLife struct {
+	Date    time.Time
+	Reached bool
+}
+
+func (e *EndOfLife) UnmarshalJSON(data []byte) error {
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	switch v := value.(type) {
+	case bool:
+		*e = EndOfLife{Reached: v}
+	case string:
+		date, err := time.Parse(dateLayout, v)
+		if err != nil {
+			return fmt.Errorf("%s is not a valid end-of-life date, %s", v, err)
+		}
+		*e = EndOfLife{Date: date}
+	case nil:
+		*e = EndOfLife{}
+	default:
+		return fmt.Errorf("%s is not a valid end-of-life, expected a date or boolean", data)
+	}
+	return nil
+}
+
+func (e EndOfLife) MarshalJSON() ([]byte, error) {
+	if e.Date.IsZero() {
+		return json.Marshal(e.Reached)
+	}
+	return json.Marshal(e.Date.Format(dateLayout))
+}
+
+// CycleName is the name of a release cycle, which is a string or a number in the dataset.
+type CycleName string
+
+func (c *CycleName) UnmarshalJSON(data []byte) error {
+	*c = CycleName(strings.Trim(string(bytes.TrimSpace(data)), `"`))
+	return nil
+}
+
+// Cycle is a release cycle of a product.
+type Cycle struct {
+	Cycle       CycleName `json:"cycle"`
+	Codename    string    `json:"codename,omitempty"`
+	ReleaseDate string    `json:"releaseDate,omitempty"`
+	EOL         EndOfLife `json:"eol"`
+	Latest      string    `json:"latest,omitempty"`
+}
+
+// IsEndOfLife returns true if the cycle is past its end-of-life at the specified time.
+func (c Cycle) IsEndOfLife(at time.Time) bool {
+	if c.EOL.Date.IsZero() {
+		return c.EOL.Reached
+	}
+	return !at.Before(c.EOL.Date)
+}
+
+// String returns the end-of-life date of the cycle, or yes or no if no date is known.
+func (c Cycle) String() string {
+	if !c.EOL.Date.IsZero() {
+		return c.EOL.Date.Format(dateLayout)
+	}
+	if c.EOL.Reached {
+		return "yes"
+	}
+	return "no"
+}
+
+// version returns the numeric components of the cycle name, like [3 7] for 3.7.
+func (c Cycle) version() []int {
+	result := make([]int, 0, 2)
+	for _, part := range strings.Split(string(c.Cycle), ".") {
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return nil
+		}
+		result = append(result, n)
+	}
+	return result
+}
+
+// Dataset maps container image repositories to products, and products to their release cycles.
+type Dataset struct {
+	Images   map[string]string  `json:"images"`
+	Products map[string][]Cycle `json:"products"`
+}
+
+// Parse reads the dataset from the JSON content. The image repositories may be abbreviated, like python.
+func Parse(content []byte) (*Dataset, error) {
+	var result Dataset
+	if err := json.Unmarshal(content, &result); err != nil {
+		return nil, fmt.Errorf("could not read end-of-life data, %s", err)
+	}
+
+	images := make(map[string]string, len(result.Images))
+	for image, product := range result.Images {
+		repository, err := name.NewRepository(image)
+		if err != nil {
+			return nil, fmt.Errorf("%s is not a valid image repository, %s", image, err)
+		}
+		if _, ok := result.Products[product]; !ok {
+			return nil, fmt.Errorf("image %s refers to unknown product %s", image, product)
+		}
+		images[repository.Name()] = product
+	}
+	result.Images = images
+	return &result, nil
+}
+
+// Load reads the dataset from the file.
+func Load(filename string) (*Dataset, error) {
+	content, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(content)
+}
+
+// Snapshot returns the built-in snapshot of the dataset.
+func Snapshot() *Dataset {
+	result, err := Parse([]byte(snapshot))
+	if err != nil {
+		panic(err)
+	}
+	return result
+}
+
+var codenameRegExp = regexp.MustCompile(`^[a-z]+`)
+
+// Lookup returns the release cycle of the reference. A cycle matches if the tag starts with its version,
+// like 3.7-slim for cycle 3.7, or with its codename, like buster-slim for Debian 10 "Buster". If multiple
+// cycles match, the most specific version is returned.
+func (d *Dataset) Lookup(reference name.Tag) (*Cycle, bool) {
+	if d == nil {
+		return nil, false
+	}
+	product, ok := d.Images[reference.Context().Name()]
+	if !ok {
+		return nil, false
+	}
+
+	t := tag.MakeTag(reference.TagStr())
+	codename := codenameRegExp.FindString(reference.TagStr())
+	var result *Cycle
+	var length int
+	for i, cycle := range d.Products[product] {
+		if codename != "" && cycle.Codename != "" &&
+			strings.EqualFold(codename, strings.Fields(cycle.Codename)[0]) {
+			return &d.Products[product][i], true
+		}
+		version := cycle.version()
+		if len(version) == 0 || len(version) <= length || t.Prefix != "" || len(t.Version) < len(version) {
+			continue
+		}
+		if equals(t.Version[:len(version)], version) {
+			result, length = &d.Products[product][i], len(version)
+		}
+	}
+	return result, result != nil
+}
+
+func equals(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
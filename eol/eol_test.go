@@ -0,0 +1,88 @@
+package eol
+
+import (
+	"github.com/google/go-containerregistry/pkg/name"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLookup(t *testing.T) {
+	dataset := Snapshot()
+	at := time.Date(2025, time.June, 1, 0, 0, 0, 0, time.UTC)
+
+	var tests = []struct {
+		reference string
+		cycle     string
+		endOfLife bool
+		date      string
+	}{
+		{"python:3.7", "3.7", true, "2023-06-27"},
+		{"python:3.7.17-slim-buster", "3.7", true, "2023-06-27"},
+		{"python:3.12", "3.12", false, "2028-10-31"},
+		{"node:14", "14", true, "2023-04-30"},
+		{"node:20-alpine", "20", false, "2026-04-30"},
+		{"debian:buster", "10", true, "2022-09-10"},
+		{"debian:bookworm-slim", "12", false, "2026-06-10"},
+		{"ubuntu:focal-20230605", "20.04", true, "2025-05-29"},
+		{"ubuntu:22.04", "22.04", false, "2027-04-01"},
+		{"docker.io/library/golang:1.24.1", "1.24", false, "no"},
+		{"python:3", "", false, ""},
+		{"python:latest", "", false, ""},
+		{"gcr.io/distroless/base:latest", "", false, ""},
+	}
+	for _, test := range tests {
+		reference, err := name.NewTag(test.reference)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cycle, ok := dataset.Lookup(reference)
+		if ok != (test.cycle != "") {
+			t.Fatalf("expected cycle %q for %s, got %v", test.cycle, test.reference, cycle)
+		}
+		if !ok {
+			continue
+		}
+		if string(cycle.Cycle) != test.cycle {
+			t.Fatalf("expected cycle %s for %s, got %s", test.cycle, test.reference, cycle.Cycle)
+		}
+		if cycle.IsEndOfLife(at) != test.endOfLife {
+			t.Fatalf("expected end-of-life %v for %s, got %v", test.endOfLife, test.reference, !test.endOfLife)
+		}
+		if cycle.String() != test.date {
+			t.Fatalf("expected end-of-life %s for %s, got %s", test.date, test.reference, cycle)
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "eol")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "eol.json")
+	content := `{"images": {"registry.corp/base/python": "python"},
+		"products": {"python": [{"cycle": 3.7, "eol": true}, {"cycle": "3.12", "eol": false}]}}`
+	if err = ioutil.WriteFile(filename, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	dataset, err := Load(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reference, _ := name.NewTag("registry.corp/base/python:3.7-slim")
+	if cycle, ok := dataset.Lookup(reference); !ok || !cycle.IsEndOfLife(time.Now()) || cycle.String() != "yes" {
+		t.Fatalf("expected python 3.7 to be end-of-life, got %v", cycle)
+	}
+
+	if _, err = Parse([]byte(`{"images": {"python": "cpython"}, "products": {}}`)); err == nil {
+		t.Fatalf("expected an error for an unknown product")
+	}
+	if _, err = Parse([]byte(`{"images": {}, "products": {"python": [{"cycle": "3.7", "eol": 1}]}}`)); err == nil {
+		t.Fatalf("expected an error for an invalid end-of-life")
+	}
+}
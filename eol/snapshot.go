@@ -0,0 +1,113 @@
+package eol
+
+// snapshot is the built-in end-of-life dataset, with the release cycles of the products from
+// https://endoflife.date as of 2025-06-01. Use a dataset file for up to date information.
+const snapshot = `{
+  "images": {
+    "python": "python",
+    "pypy": "python",
+    "node": "nodejs",
+    "golang": "go",
+    "debian": "debian",
+    "ubuntu": "ubuntu",
+    "alpine": "alpine",
+    "eclipse-temurin": "eclipse-temurin",
+    "openjdk": "eclipse-temurin",
+    "postgres": "postgresql",
+    "redis": "redis",
+    "nginx": "nginx"
+  },
+  "products": {
+    "python": [
+      {"cycle": "3.13", "releaseDate": "2024-10-07", "eol": "2029-10-31"},
+      {"cycle": "3.12", "releaseDate": "2023-10-02", "eol": "2028-10-31"},
+      {"cycle": "3.11", "releaseDate": "2022-10-24", "eol": "2027-10-31"},
+      {"cycle": "3.10", "releaseDate": "2021-10-04", "eol": "2026-10-31"},
+      {"cycle": "3.9", "releaseDate": "2020-10-05", "eol": "2025-10-31"},
+      {"cycle": "3.8", "releaseDate": "2019-10-14", "eol": "2024-10-07"},
+      {"cycle": "3.7", "releaseDate": "2018-06-27", "eol": "2023-06-27"},
+      {"cycle": "3.6", "releaseDate": "2016-12-23", "eol": "2021-12-23"},
+      {"cycle": "2.7", "releaseDate": "2010-07-03", "eol": "2020-01-01"}
+    ],
+    "nodejs": [
+      {"cycle": "24", "releaseDate": "2025-05-06", "eol": "2028-04-30"},
+      {"cycle": "23", "releaseDate": "2024-10-16", "eol": "2025-06-01"},
+      {"cycle": "22", "releaseDate": "2024-04-24", "eol": "2027-04-30"},
+      {"cycle": "21", "releaseDate": "2023-10-17", "eol": "2024-06-01"},
+      {"cycle": "20", "releaseDate": "2023-04-18", "eol": "2026-04-30"},
+      {"cycle": "19", "releaseDate": "2022-10-18", "eol": "2023-06-01"},
+      {"cycle": "18", "releaseDate": "2022-04-19", "eol": "2025-04-30"},
+      {"cycle": "16", "releaseDate": "2021-04-20", "eol": "2023-09-11"},
+      {"cycle": "14", "releaseDate": "2020-04-21", "eol": "2023-04-30"},
+      {"cycle": "12", "releaseDate": "2019-04-23", "eol": "2022-04-30"},
+      {"cycle": "10", "releaseDate": "2018-04-24", "eol": "2021-04-30"}
+    ],
+    "go": [
+      {"cycle": "1.24", "releaseDate": "2025-02-11", "eol": false},
+      {"cycle": "1.23", "releaseDate": "2024-08-13", "eol": false},
+      {"cycle": "1.22", "releaseDate": "2024-02-06", "eol": "2025-02-11"},
+      {"cycle": "1.21", "releaseDate": "2023-08-08", "eol": "2024-08-13"},
+      {"cycle": "1.20", "releaseDate": "2023-02-01", "eol": "2024-02-06"},
+      {"cycle": "1.19", "releaseDate": "2022-08-02", "eol": "2023-09-06"},
+      {"cycle": "1.18", "releaseDate": "2022-03-15", "eol": "2023-02-01"}
+    ],
+    "debian": [
+      {"cycle": "12", "codename": "Bookworm", "releaseDate": "2023-06-10", "eol": "2026-06-10"},
+      {"cycle": "11", "codename": "Bullseye", "releaseDate": "2021-08-14", "eol": "2024-08-14"},
+      {"cycle": "10", "codename": "Buster", "releaseDate": "2019-07-06", "eol": "2022-09-10"},
+      {"cycle": "9", "codename": "Stretch", "releaseDate": "2017-06-17", "eol": "2020-07-18"},
+      {"cycle": "8", "codename": "Jessie", "releaseDate": "2015-04-25", "eol": "2018-06-17"}
+    ],
+    "ubuntu": [
+      {"cycle": "25.04", "codename": "Plucky Puffin", "releaseDate": "2025-04-17", "eol": "2026-01-15"},
+      {"cycle": "24.10", "codename": "Oracular Oriole", "releaseDate": "2024-10-10", "eol": "2025-07-10"},
+      {"cycle": "24.04", "codename": "Noble Numbat", "releaseDate": "2024-04-25", "eol": "2029-05-31"},
+      {"cycle": "22.04", "codename": "Jammy Jellyfish", "releaseDate": "2022-04-21", "eol": "2027-04-01"},
+      {"cycle": "20.04", "codename": "Focal Fossa", "releaseDate": "2020-04-23", "eol": "2025-05-29"},
+      {"cycle": "18.04", "codename": "Bionic Beaver", "releaseDate": "2018-04-26", "eol": "2023-05-31"},
+      {"cycle": "16.04", "codename": "Xenial Xerus", "releaseDate": "2016-04-21", "eol": "2021-04-30"},
+      {"cycle": "14.04", "codename": "Trusty Tahr", "releaseDate": "2014-04-17", "eol": "2019-04-25"}
+    ],
+    "alpine": [
+      {"cycle": "3.22", "releaseDate": "2025-05-30", "eol": "2027-05-01"},
+      {"cycle": "3.21", "releaseDate": "2024-12-05", "eol": "2026-11-01"},
+      {"cycle": "3.20", "releaseDate": "2024-05-22", "eol": "2026-04-01"},
+      {"cycle": "3.19", "releaseDate": "2023-12-07", "eol": "2025-11-01"},
+      {"cycle": "3.18", "releaseDate": "2023-05-09", "eol": "2025-05-09"},
+      {"cycle": "3.17", "releaseDate": "2022-11-22", "eol": "2024-11-22"},
+      {"cycle": "3.16", "releaseDate": "2022-05-23", "eol": "2024-05-23"},
+      {"cycle": "3.15", "releaseDate": "2021-11-24", "eol": "2023-11-01"},
+      {"cycle": "3.14", "releaseDate": "2021-06-15", "eol": "2023-05-01"}
+    ],
+    "eclipse-temurin": [
+      {"cycle": "24", "releaseDate": "2025-03-18", "eol": "2025-09-30"},
+      {"cycle": "21", "releaseDate": "2023-09-19", "eol": "2029-12-31"},
+      {"cycle": "17", "releaseDate": "2021-09-14", "eol": "2027-10-31"},
+      {"cycle": "11", "releaseDate": "2018-09-25", "eol": "2027-10-31"},
+      {"cycle": "8", "releaseDate": "2014-03-18", "eol": "2030-12-31"}
+    ],
+    "postgresql": [
+      {"cycle": "17", "releaseDate": "2024-09-26", "eol": "2029-11-08"},
+      {"cycle": "16", "releaseDate": "2023-09-14", "eol": "2028-11-09"},
+      {"cycle": "15", "releaseDate": "2022-10-13", "eol": "2027-11-11"},
+      {"cycle": "14", "releaseDate": "2021-09-30", "eol": "2026-11-12"},
+      {"cycle": "13", "releaseDate": "2020-09-24", "eol": "2025-11-13"},
+      {"cycle": "12", "releaseDate": "2019-10-03", "eol": "2024-11-21"},
+      {"cycle": "11", "releaseDate": "2018-10-18", "eol": "2023-11-09"}
+    ],
+    "redis": [
+      {"cycle": "7.4", "releaseDate": "2024-07-29", "eol": false},
+      {"cycle": "7.2", "releaseDate": "2023-08-15", "eol": false},
+      {"cycle": "7.0", "releaseDate": "2022-04-27", "eol": "2025-02-28"},
+      {"cycle": "6.2", "releaseDate": "2021-02-22", "eol": "2025-02-28"},
+      {"cycle": "6.0", "releaseDate": "2020-04-30", "eol": "2022-04-27"}
+    ],
+    "nginx": [
+      {"cycle": "1.28", "releaseDate": "2025-04-23", "eol": false},
+      {"cycle": "1.27", "releaseDate": "2024-05-29", "eol": false},
+      {"cycle": "1.26", "releaseDate": "2024-04-23", "eol": "2025-04-23"},
+      {"cycle": "1.25", "releaseDate": "2023-05-23", "eol": "2024-04-23"},
+      {"cycle": "1.24", "releaseDate": "2023-04-11", "eol": "2024-04-23"}
+    ]
+  }
+}`
@@ -0,0 +1,172 @@
+// Package history reads the changes made by fromage from the commit log of a git repository.
+package history
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/binxio/fromage/repository"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// historyDepth is the number of commits searched on every branch.
+const historyDepth = 1000
+
+// Change is a container image reference changed by fromage, as recorded in the Fromage-Change trailer.
+type Change struct {
+	Path string `json:"path"`
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// ParseChange parses the value of a Fromage-Change trailer, `path from -> to`.
+func ParseChange(value string) (Change, error) {
+	fields := strings.Fields(value)
+	if len(fields) != 4 || fields[2] != "->" {
+		return Change{}, fmt.Errorf("invalid change %q, expected `path from -> to`", value)
+	}
+	return Change{Path: fields[0], From: fields[1], To: fields[3]}, nil
+}
+
+// Entry is a commit made by fromage.
+type Entry struct {
+	Commit     string    `json:"commit"`
+	Branches   []string  `json:"branches"`
+	Time       time.Time `json:"time"`
+	Subject    string    `json:"subject"`
+	Changes    []Change  `json:"changes"`
+	Reverts    string    `json:"reverts,omitempty"`
+	Pin        string    `json:"pin,omitempty"`
+	VariantPin string    `json:"pin-variant,omitempty"`
+	Version    string    `json:"version,omitempty"`
+}
+
+// Entries is a timeline of commits made by fromage, oldest first.
+type Entries []*Entry
+
+// NewEntry returns the entry for the commit, from its trailers. Commits made before fromage recorded its
+// changes in trailers have no changes.
+func NewEntry(commit *object.Commit) *Entry {
+	result := &Entry{
+		Commit:   commit.Hash.String(),
+		Branches: make([]string, 0, 1),
+		Time:     commit.Author.When,
+		Subject:  strings.SplitN(strings.TrimSpace(commit.Message), "\n", 2)[0],
+		Changes:  make([]Change, 0),
+	}
+	for _, value := range repository.Trailers(commit.Message, repository.ChangeTrailer) {
+		if change, err := ParseChange(value); err == nil {
+			result.Changes = append(result.Changes, change)
+		}
+	}
+	result.Reverts = lastTrailer(commit.Message, repository.RevertTrailer)
+	result.Pin = lastTrailer(commit.Message, repository.PinTrailer)
+	result.VariantPin = lastTrailer(commit.Message, repository.VariantPinTrailer)
+	result.Version = lastTrailer(commit.Message, repository.VersionTrailer)
+	return result
+}
+
+func lastTrailer(message string, key string) string {
+	values := repository.Trailers(message, key)
+	if len(values) == 0 {
+		return ""
+	}
+	return values[len(values)-1]
+}
+
+// Read returns the commits made by fromage on the branches of the repository, in the order they were made. A
+// commit reachable from several branches is listed once. If no branches are specified, all branches are read.
+func Read(ctx context.Context, r *repository.Repository, branches []string) (Entries, error) {
+	heads := make([]*plumbing.Reference, 0)
+	if r.IsWorktree() {
+		head, err := r.Head("")
+		if err != nil {
+			return nil, err
+		}
+		heads = append(heads, head)
+	} else {
+		iter, err := r.Branches()
+		if err != nil {
+			return nil, err
+		}
+		_ = iter.ForEach(func(reference *plumbing.Reference) error {
+			if repository.DesiredBranch(reference, branches) {
+				heads = append(heads, reference)
+			}
+			return nil
+		})
+	}
+
+	entries := make(map[string]*Entry)
+	for _, head := range heads {
+		if err := r.Deepen(ctx, head.Name().Short(), historyDepth); err != nil {
+			return nil, err
+		}
+		if !r.IsWorktree() {
+			var err error
+			if head, err = r.Head(head.Name().Short()); err != nil {
+				return nil, err
+			}
+		}
+		count := 0
+		err := r.Log(head, func(commit *object.Commit) (bool, error) {
+			count++
+			if repository.IsFromageCommit(commit) {
+				entry, ok := entries[commit.Hash.String()]
+				if !ok {
+					entry = NewEntry(commit)
+					entries[entry.Commit] = entry
+				}
+				entry.Branches = append(entry.Branches, head.Name().Short())
+			}
+			return count < historyDepth, nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	result := make(Entries, 0, len(entries))
+	for _, entry := range entries {
+		result = append(result, entry)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Time.Equal(result[j].Time) {
+			return result[i].Commit < result[j].Commit
+		}
+		return result[i].Time.Before(result[j].Time)
+	})
+	return result, nil
+}
+
+// Output writes the entries as JSON, or as text with a line for every change.
+func (e Entries) Output(out io.Writer, format string, noHeader bool) error {
+	if format == "json" {
+		encoder := json.NewEncoder(out)
+		return encoder.Encode(e)
+	} else if format != "" && format != "text" {
+		return fmt.Errorf("unsupported format %s, expected text or json", format)
+	}
+
+	w := tabwriter.NewWriter(out, 1, 8, 1, '\t', tabwriter.TabIndent)
+	if !noHeader {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", "TIME", "COMMIT", "BRANCH", "PATH", "FROM", "TO")
+	}
+	for _, entry := range e {
+		when := entry.Time.UTC().Format(time.RFC3339)
+		branches := strings.Join(entry.Branches, ",")
+		if len(entry.Changes) == 0 {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", when, entry.Commit[0:7], branches, "-", "-", "-")
+		}
+		for _, change := range entry.Changes {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", when, entry.Commit[0:7], branches, change.Path, change.From, change.To)
+		}
+	}
+	return w.Flush()
+}
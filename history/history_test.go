@@ -0,0 +1,116 @@
+package history
+
+import (
+	"bytes"
+	"context"
+	"github.com/binxio/fromage/bump"
+	"github.com/binxio/fromage/dockerfile"
+	"github.com/binxio/fromage/repository"
+	"github.com/binxio/fromage/tag"
+	"gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseChange(t *testing.T) {
+	var tests = []struct {
+		value  string
+		expect Change
+		valid  bool
+	}{
+		{"deploy/Dockerfile golang:1.12 -> golang:1.13", Change{"deploy/Dockerfile", "golang:1.12", "golang:1.13"}, true},
+		{"Dockerfile  alpine:3.17  ->  alpine:3.18", Change{"Dockerfile", "alpine:3.17", "alpine:3.18"}, true},
+		{"Dockerfile alpine:3.17 alpine:3.18", Change{}, false},
+		{"Dockerfile", Change{}, false},
+	}
+	for _, test := range tests {
+		result, err := ParseChange(test.value)
+		if (err == nil) != test.valid {
+			t.Fatalf("expected %q to be valid %v, got %v", test.value, test.valid, err)
+		}
+		if result != test.expect {
+			t.Fatalf("expected %q to parse as %v, got %v", test.value, test.expect, result)
+		}
+	}
+}
+
+func commit(t *testing.T, g *git.Repository, dir string, content string, message string, email string, when time.Time) {
+	if err := ioutil.WriteFile(filepath.Join(dir, "Dockerfile"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	worktree, err := g.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = worktree.Add("Dockerfile"); err != nil {
+		t.Fatal(err)
+	}
+	_, err = worktree.Commit(message, &git.CommitOptions{
+		Author: &object.Signature{Name: "test", Email: email, When: when},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRead(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fromage-history")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	g, err := git.PlainInit(dir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	minor := tag.Level(tag.MINOR)
+	result := &bump.Result{Changes: dockerfile.Changes{
+		{Path: "Dockerfile", From: "golang:1.12", To: "golang:1.13"},
+		{Path: "Dockerfile", From: "alpine:3.16", To: "alpine:3.17"},
+	}}
+	start := time.Date(2021, 1, 21, 21, 5, 0, 0, time.UTC)
+	commit(t, g, dir, "FROM golang:1.12\nFROM alpine:3.16\n", "initial", "test@example.com", start)
+	commit(t, g, dir, "FROM golang:1.11\nFROM alpine:3.16\n", "container image references bumped", repository.AuthorEmail,
+		start.Add(time.Hour))
+	commit(t, g, dir, "FROM golang:1.13\nFROM alpine:3.17\n", result.CommitMessage("container image references bumped",
+		bump.Options{Pin: &minor}, "v1.0.0"), "test@example.com", start.Add(2*time.Hour))
+
+	r, err := repository.Open(context.Background(), dir, false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries, err := Read(context.Background(), r, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 commits by fromage, got %d", len(entries))
+	}
+	if len(entries[0].Changes) != 0 {
+		t.Fatalf("expected no changes for a commit without trailers, got %v", entries[0].Changes)
+	}
+	last := entries[1]
+	if len(last.Changes) != 2 || last.Changes[1] != (Change{"Dockerfile", "alpine:3.16", "alpine:3.17"}) {
+		t.Fatalf("expected the changes of the trailers, got %v", last.Changes)
+	}
+	if last.Pin != "minor" || last.Version != "v1.0.0" || last.Subject != "container image references bumped" {
+		t.Fatalf("expected pin minor, version v1.0.0 and the subject, got %s, %s and %s", last.Pin, last.Version, last.Subject)
+	}
+	if len(last.Branches) != 1 || last.Branches[0] != "master" {
+		t.Fatalf("expected the commit on master, got %v", last.Branches)
+	}
+
+	var out bytes.Buffer
+	if err = entries.Output(&out, "text", false); err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Split(strings.TrimSpace(out.String()), "\n"); len(lines) != 4 {
+		t.Fatalf("expected a header and a line for every change, got\n%s", out.String())
+	}
+}
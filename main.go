@@ -1,350 +1,494 @@
 package main
 
 import (
-	"bytes"
+	"context"
 	"fmt"
+	"github.com/binxio/fromage/bump"
+	"github.com/binxio/fromage/daemon"
+	"github.com/binxio/fromage/deprecation"
+	"github.com/binxio/fromage/dockerfile"
+	"github.com/binxio/fromage/eol"
+	"github.com/binxio/fromage/history"
+	"github.com/binxio/fromage/policy"
+	"github.com/binxio/fromage/repository"
+	"github.com/binxio/fromage/scan"
+	"github.com/binxio/fromage/server"
 	"github.com/binxio/fromage/tag"
 	"github.com/docopt/docopt-go"
-	"github.com/google/go-containerregistry/pkg/name"
-	"gopkg.in/src-d/go-git.v4"
-	"gopkg.in/src-d/go-git.v4/plumbing"
-	"gopkg.in/src-d/go-git.v4/plumbing/object"
-	"gopkg.in/src-d/go-git.v4/plumbing/storer"
-	"io"
-	"io/ioutil"
 	"log"
 	"os"
-	"path"
+	"os/signal"
+	"path/filepath"
 	"strings"
+	"syscall"
+	"text/template"
 	"time"
 )
 
+// version of fromage, recorded in the commits it makes. It is set at build time with
+// -ldflags "-X main.version=...".
+var version = "dev"
+
 type Fromage struct {
-	Check          bool
-	List           bool
-	Bump           bool
-	Move           bool
-	Format         string
-	OnlyReferences bool
-	NoHeader       bool
-	Branch         []string
-	Url            string
-	DryRun         bool
-	Verbose        bool
-	Pin            string
-	Latest         bool
-	From, To       string
-
-	repository    *git.Repository
-	workTree      *git.Worktree
-	currentBranch *plumbing.Reference
-	dockerfile    string
-	references    DockerfileFromReferences
-	pin           *tag.Level
-	updated       bool
+	Check             bool
+	List              bool
+	Bump              bool
+	Move              bool
+	Revert            bool
+	History           bool
+	Lint              bool
+	Serve             bool
+	Daemon            bool
+	WhoUses           bool `docopt:"who-uses"`
+	Image             string
+	Match             string
+	Digest            bool
+	Config            string
+	Interval          string
+	Policy            string
+	Format            string
+	OnlyReferences    bool
+	NoHeader          bool
+	Branch            []string
+	RefPattern        []string
+	Path              []string
+	Url               string
+	DryRun            bool
+	Verbose           bool
+	Pin               string
+	PinVariant        string
+	FailOn            string
+	MaxAge            string
+	EolData           string
+	FailOnEol         bool
+	Deprecations      string
+	Deprecated        bool
+	Latest            bool
+	PinFloating       bool
+	OnlyFinalStage    bool
+	SkipBuilderStages bool
+	Platforms         string
+	From, To          string
+	Worktree          string
+	Output            string
+	Template          string
+	TemplateFile      string
+	Listen            string
+	CacheDir          string
+	Status            string
+	ApiUrl            string
+
+	repository   *repository.Repository
+	pin          *tag.Level
+	variantPin   *tag.Level
+	failOn       []tag.Level
+	maxAge       time.Duration
+	endOfLife    *eol.Dataset
+	deprecations deprecation.Rules
+	platforms    tag.Platforms
+	paths        repository.PathFilter
+	state        *scan.State
+	template     *template.Template
 }
 
-func (f *Fromage) IsLocalRepository() bool {
-	return !MatchesScheme(f.Url) && !MatchesScpLike(f.Url)
+func (f *Fromage) ReadOnly() bool {
+	return f.Check || f.List || f.Lint || f.History || f.DryRun
 }
 
-func FindDockerfiles(wt *git.Worktree, filename string, ref *plumbing.Reference) ([]string, error) {
-	result := make([]string, 0)
-	file, err := wt.Filesystem.Stat(filename)
-	if err != nil {
-		return nil, err
-	}
-	if file.IsDir() {
-		dir, err := wt.Filesystem.ReadDir(filename)
-		if err != nil {
-			return nil, err
-		}
+func (f *Fromage) OpenRepository(ctx context.Context) {
+	var err error
 
-		for _, file = range dir {
-			fullPath := path.Join(filename, file.Name())
-			if filename == "/" {
-				fullPath = file.Name()
-			}
-			found, err := FindDockerfiles(wt, fullPath, ref)
-			if err == nil {
-				result = append(result, found...)
-			} else {
-				return nil, err
-			}
-		}
+	if f.Worktree != "" {
+		f.repository, err = repository.OpenWorktree(f.Worktree, f.Verbose)
+	} else if f.CacheDir != "" && (f.List || f.Check) {
+		f.repository, err = repository.OpenMirror(ctx, f.Url, f.CacheDir, f.Verbose)
 	} else {
-		if path.Base(file.Name()) == "Dockerfile" {
-			result = append(result, filename)
-		}
+		f.repository, err = repository.Open(ctx, f.Url, f.ReadOnly(), f.Verbose)
 	}
-	return result, nil
-}
-
-func ReadFile(wt *git.Worktree, filename string) ([]byte, error) {
-	file, err := wt.Filesystem.Open(filename)
 	if err != nil {
-		return nil, err
+		log.Fatalf("ERROR: %s", err)
 	}
-	defer file.Close()
-	content, err := ioutil.ReadAll(file)
-	if err != nil {
-		return nil, err
+
+	if err = f.repository.CheckBranches(f.Branch); err != nil {
+		log.Fatalf("ERROR: %s", err)
 	}
-	return content, nil
+	f.repository.Paths = f.paths
 }
 
-func WriteFile(wt *git.Worktree, filename string, content []byte) error {
-	file, err := wt.Filesystem.Create(filename)
-	if err != nil {
-		return err
-	}
-	defer file.Close()
-	_, err = file.Write(content)
-	if err != nil {
-		return err
+func (f *Fromage) ScanOptions() scan.Options {
+	return scan.Options{
+		Branches:     f.Branch,
+		RefPatterns:  f.RefPattern,
+		Pin:          f.pin,
+		VariantPin:   f.variantPin,
+		Platforms:    f.platforms,
+		EndOfLife:    f.endOfLife,
+		Deprecations: f.deprecations,
+		State:        f.state,
+		FailOn:       f.failOn,
+		MaxAge:       f.maxAge,
+		Ages:         f.ShowsAges(),
+		FailOnEol:    f.FailOnEol,
 	}
+}
 
-	_, err = wt.Add(filename)
-	return err
+// ShowsAges returns true if the output shows the age of the images. The sarif, junit and markdown formats
+// do not, nor do the commit statuses and metrics of serve and daemon.
+func (f *Fromage) ShowsAges() bool {
+	if f.OnlyReferences || f.Serve || f.Daemon {
+		return false
+	}
+	return f.Format != "sarif" && f.Format != "junit" && f.Format != "markdown"
 }
 
-func ReadFromStatements(wt *git.Worktree, filename string) ([]string, error) {
-	content, err := ReadFile(wt, filename)
-	if err != nil {
-		return nil, err
+func (f *Fromage) BumpOptions() bump.Options {
+	return bump.Options{
+		Branches:    f.Branch,
+		Pin:         f.pin,
+		VariantPin:  f.variantPin,
+		Latest:      f.Latest,
+		Platforms:   f.platforms,
+		PinFloating: f.PinFloating,
+		Stages:      f.StageSelection(),
+		DryRun:      f.DryRun,
+		Verbose:     f.Verbose,
 	}
-	return ExtractFromStatements(content), nil
 }
 
-func DesiredBranch(reference *plumbing.Reference, branches []string) bool {
-	if !reference.Name().IsBranch() {
-		return false
+// ServeWebhooks checks the branches pushed to the repositories announced by webhooks, until interrupted.
+func (f *Fromage) ServeWebhooks(ctx context.Context) error {
+	secret := os.Getenv("FROMAGE_WEBHOOK_SECRET")
+	if secret == "" {
+		return fmt.Errorf("FROMAGE_WEBHOOK_SECRET is required to verify the webhooks")
 	}
-	for _, branch := range branches {
-		if branch == reference.Name().Short() || branch == reference.Name().String() {
-			return true
+	options := server.Options{Secret: secret, Scan: f.ScanOptions(), CacheDir: f.CacheDir, Verbose: f.Verbose}
+	options.Scan.State = nil
+	if f.Status != "" {
+		client, err := server.NewStatusClient(f.Status, f.ApiUrl, os.Getenv("FROMAGE_STATUS_TOKEN"))
+		if err != nil {
+			return err
 		}
+		options.Status = client
 	}
-	return len(branches) == 0
-}
 
-func (f *Fromage) ReadOnly() bool {
-	return f.Check || f.List || f.DryRun
+	ctx, cancel := interruptible(ctx)
+	defer cancel()
+	return server.New(options).ListenAndServe(ctx, f.Listen)
 }
 
-func (f *Fromage) OpenRepository() {
-	var err error
-
-	if f.Verbose {
-		f.repository, err = Clone(f.Url, os.Stderr, f.ReadOnly())
-	} else {
-		f.repository, err = Clone(f.Url, &bytes.Buffer{}, f.ReadOnly())
-	}
-
+// RunDaemon scans the repositories of the configuration every interval and serves the metrics, until
+// interrupted.
+func (f *Fromage) RunDaemon(ctx context.Context) error {
+	config, err := daemon.Load(f.Config)
 	if err != nil {
-		log.Printf("ERROR: failed to clone repository %s, %s", f.Url, err)
-		os.Exit(1)
+		return err
 	}
-
-	f.workTree, err = f.repository.Worktree()
-	if err != nil {
-		log.Printf("ERROR: failed to get repository worktree of %s, %s", f.Url, err)
-		os.Exit(1)
+	interval, err := time.ParseDuration(f.Interval)
+	if err != nil || interval <= 0 {
+		return fmt.Errorf("%s is not a valid interval, expected a duration like 6h", f.Interval)
 	}
 
-	f.references = make(DockerfileFromReferences, 0)
-
-	for _, branch := range f.Branch {
-		found := false
-		_ = f.Branches().ForEach(func(reference *plumbing.Reference) error {
-			found = found || (branch == reference.Name().Short() || branch == reference.Name().String())
-			return nil
-		})
-		if !found {
-			log.Printf("ERROR: branch %s does not exist", branch)
-			os.Exit(1)
-		}
-	}
+	ctx, cancel := interruptible(ctx)
+	defer cancel()
+	d := daemon.New(config, daemon.Options{Interval: interval, Scan: f.ScanOptions(), CacheDir: f.CacheDir,
+		Verbose: f.Verbose})
+	return d.ListenAndServe(ctx, f.Listen)
 }
 
-func (f Fromage) Branches() storer.ReferenceIter {
-	branches, err := f.repository.Branches()
+// FindUsers returns the references to the image in the repository, or in the repositories of the
+// configuration.
+func (f *Fromage) FindUsers(ctx context.Context) (scan.DockerfileFromReferences, error) {
+	matcher, err := scan.NewImageMatcher(f.Image, f.Match, f.Digest)
 	if err != nil {
-		log.Printf("failed retrieve branches of repository %s, %s", f.Url, err)
-		os.Exit(1)
+		return nil, err
+	}
+	if f.Config == "" {
+		f.OpenRepository(ctx)
+		return scan.FindReferences(ctx, f.repository, f.Branch, matcher)
 	}
-	return branches
-}
-
-func (f *Fromage) ForEachDockerfile(m func(f *Fromage) error) error {
-	return f.Branches().ForEach(func(ref *plumbing.Reference) error {
-		f.currentBranch = ref
-
-		if !DesiredBranch(ref, f.Branch) {
-			return nil
-		}
 
-		if f.Verbose {
-			log.Printf("checking out %s\n", ref.Name().Short())
+	config, err := daemon.Load(f.Config)
+	if err != nil {
+		return nil, err
+	}
+	result := make(scan.DockerfileFromReferences, 0)
+	for _, r := range config.Repositories {
+		repo, err := repository.Open(ctx, r.Url, true, f.Verbose)
+		if err == nil {
+			repo.Paths = f.paths
+			err = repo.CheckBranches(r.Branches)
 		}
-
-		err := f.workTree.Checkout(&git.CheckoutOptions{
-			Branch: ref.Name(),
-			Force:  false,
-		})
-		if err != nil {
-			return fmt.Errorf("ERROR: checkout of %s failed, %s", ref.Name().Short(), err)
+		var references scan.DockerfileFromReferences
+		if err == nil {
+			references, err = scan.FindReferences(ctx, repo, r.Branches, matcher)
 		}
-
-		dockerfiles, err := FindDockerfiles(f.workTree, "/", ref)
 		if err != nil {
-			return err
-		}
-		for _, f.dockerfile = range dockerfiles {
-			if err = m(f); err != nil {
-				return err
-			}
+			log.Printf("WARNING: skipping %s, %s", r.Url, err)
+			continue
 		}
-		return nil
-	})
-}
-
-func ListAllReferences(f *Fromage) error {
-	references, err := ReadFromStatements(f.workTree, f.dockerfile)
-	if err != nil {
-		return err
+		result = append(result, references...)
 	}
-	for _, reference := range references {
-
-		var newer []string
-		if successors, err := tag.GetAllSuccessorsByString(reference, f.pin); err == nil {
-			newer = make([]string, 0, len(successors))
-			for _, v := range successors {
-				newer = append(newer, v.String())
-			}
-		}
+	return result, nil
+}
 
-		froms := DockerfileFromReference{
-			Branch:    f.currentBranch.Name().Short(),
-			Path:      f.dockerfile,
-			Reference: reference,
-			Newer:     newer,
-		}
-		f.references = append(f.references, &froms)
-	}
-	return nil
+// interruptible returns a context which is cancelled on an interrupt or termination signal.
+func interruptible(ctx context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(ctx)
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		select {
+		case <-signals:
+			cancel()
+		case <-ctx.Done():
+		}
+		signal.Stop(signals)
+	}()
+	return ctx, cancel
 }
 
-func BumpReferences(f *Fromage) error {
-	content, err := ReadFile(f.workTree, f.dockerfile)
-	if err != nil {
-		return err
+func (f *Fromage) StageSelection() dockerfile.StageSelection {
+	if f.OnlyFinalStage {
+		return dockerfile.OnlyFinalStage
+	} else if f.SkipBuilderStages {
+		return dockerfile.SkipBuilderStages
 	}
+	return dockerfile.AllStages
+}
 
-	content, updated := UpdateAllFromStatements(content, f.dockerfile, f.pin, f.Latest, f.Verbose)
-	if updated {
-		f.updated = true
-		if !f.DryRun {
-			return WriteFile(f.workTree, f.dockerfile, content)
+func (f *Fromage) ParseTemplate() (err error) {
+	if f.Format != "template" {
+		if f.Template != "" || f.TemplateFile != "" {
+			return fmt.Errorf("--template and --template-file require --format=template")
 		}
+		return nil
 	}
-
-	return nil
+	if f.TemplateFile != "" {
+		f.template, err = scan.ParseTemplateFile(f.TemplateFile)
+	} else if f.Template != "" {
+		f.template, err = scan.ParseTemplate(f.Template)
+	} else {
+		err = fmt.Errorf("--format=template requires --template or --template-file")
+	}
+	return err
 }
 
-func moveImageReferences(content []byte, filename string, verbose bool, from, to string) ([]byte, bool, error) {
-	updated := false
-	refs := ExtractFromStatements(content)
-	for _, refString := range refs {
-		ref, err := name.ParseReference(refString)
-		fullRef := ref.Name()
-
-		if err != nil {
-			return nil, false, err
-		}
-
-		if !strings.HasPrefix(fullRef, from) && len(fullRef) > len(from) {
-			continue
-		}
-
-		if delimiter := fullRef[len(from)]; delimiter != ':' && delimiter != '/' && delimiter != '@' {
-			continue
-		}
-
-		newRefString := to + fullRef[len(from):]
-		if to == "index.docker.io/library" {
-			newRefString = fullRef[len(from)+1:]
-		}
-		newRef, err := name.ParseReference(newRefString)
-		if err != nil {
-			return nil, false, err
-		}
-
-		if !RepositoryExists(newRef, verbose) {
-			return nil, false, fmt.Errorf("ERROR: %s is not a valid image reference", newRef)
-		}
-
-		ok := false
-		if content, ok = UpdateFromStatements(content, ref, newRef, filename, verbose); ok {
-			updated = true
+func (f *Fromage) OutputReferences(references scan.DockerfileFromReferences) error {
+	if f.template != nil {
+		if f.OnlyReferences {
+			return references.OutputOnlyReferencesTemplate(os.Stdout, f.template)
 		}
+		return references.OutputTemplate(os.Stdout, f.template)
+	}
+	if f.OnlyReferences {
+		return references.OutputOnlyReferences(os.Stdout, f.Format, f.NoHeader)
 	}
-	return content, updated, nil
+	return references.Output(os.Stdout, f.Format, f.NoHeader)
 }
 
-func MoveImageReferences(f *Fromage) error {
-	content, err := ReadFile(f.workTree, f.dockerfile)
-	if err != nil {
+func (f *Fromage) WriteChanges(result *bump.Result) error {
+	switch f.Output {
+	case "":
+		return nil
+	case "patch":
+		_, err := os.Stdout.Write(result.Patch())
 		return err
+	case "json":
+		return result.Changes.WriteJSON(os.Stdout)
+	default:
+		return fmt.Errorf("unsupported output %s, expected patch or json", f.Output)
 	}
+}
 
-	content, f.updated, err = moveImageReferences(content, f.dockerfile, f.Verbose, f.From, f.To)
-	if err != nil {
-		log.Fatalf("%s", err)
+func (f *Fromage) CommitAndPush(ctx context.Context, result *bump.Result, msg string) error {
+	if err := f.WriteChanges(result); err != nil {
+		return err
 	}
-
-	if f.updated {
-		if !f.DryRun {
-			return WriteFile(f.workTree, f.dockerfile, content)
-		}
+	if !result.Updated() {
+		return nil
 	}
-
-	return nil
+	return f.repository.CommitAndPush(ctx, result.CommitMessage(msg, f.BumpOptions(), version), f.DryRun)
 }
 
 func main() {
 	usage := `fromage - checks, list and bumps all container references in Dockerfiles in a git repository
 
 Usage:
-  fromage list  [--verbose] [--format=FORMAT] [--no-header] [--only-references]  [--branch=BRANCH ...] URL
-  fromage check [--verbose] [--format=FORMAT] [--no-header] [--only-references]  [--branch=BRANCH ...] [--pin=LEVEL] URL
-  fromage bump  [--verbose] [--dry-run] [--pin=LEVEL] [--latest] --branch=BRANCH URL
-  fromage move  [--verbose] [--dry-run] --from=FROM_REPOSITORY --to=TO_REPOSITORY --branch=BRANCH URL
+  fromage list  [--verbose] [--format=FORMAT [--template=TEMPLATE | --template-file=FILE]] [--no-header] [--only-references] [--platforms=PLATFORMS] [--eol-data=FILE] [--fail-on-eol] [--deprecations=FILE] [--cache-dir=DIR] [--path=PATTERN ...] ([--branch=BRANCH ...] [--ref-pattern=PATTERN ...] URL | --worktree=PATH)
+  fromage check [--verbose] [--format=FORMAT [--template=TEMPLATE | --template-file=FILE]] [--no-header] [--only-references] [--pin=LEVEL] [--pin-variant=LEVEL] [--fail-on=LEVELS] [--max-age=MAX_AGE] [--platforms=PLATFORMS] [--eol-data=FILE] [--fail-on-eol] [--deprecations=FILE] [--cache-dir=DIR] [--path=PATTERN ...] ([--branch=BRANCH ...] [--ref-pattern=PATTERN ...] URL | --worktree=PATH)
+  fromage bump  [--verbose] [--dry-run] [--output=OUTPUT] [--pin=LEVEL] [--pin-variant=LEVEL] [--latest] [--pin-floating] [--only-final-stage | --skip-builder-stages] [--platforms=PLATFORMS] [--path=PATTERN ...] (--branch=BRANCH URL | --worktree=PATH)
+  fromage lint  [--verbose] [--format=FORMAT [--template=TEMPLATE | --template-file=FILE]] [--no-header] --policy=FILE [--path=PATTERN ...] ([--branch=BRANCH ...] URL | --worktree=PATH)
+  fromage serve [--verbose] [--listen=ADDRESS] [--status=PROVIDER [--api-url=URL]] [--pin=LEVEL] [--pin-variant=LEVEL] [--fail-on=LEVELS] [--max-age=MAX_AGE] [--platforms=PLATFORMS] [--eol-data=FILE] [--fail-on-eol] [--deprecations=FILE] [--cache-dir=DIR]
+  fromage daemon [--verbose] --config=FILE [--interval=INTERVAL] [--listen=ADDRESS] [--pin=LEVEL] [--pin-variant=LEVEL] [--fail-on=LEVELS] [--max-age=MAX_AGE] [--platforms=PLATFORMS] [--eol-data=FILE] [--fail-on-eol] [--deprecations=FILE] [--cache-dir=DIR]
+  fromage who-uses [--verbose] [--format=FORMAT [--template=TEMPLATE | --template-file=FILE]] [--no-header] [--match=MODE] [--digest] IMAGE [--path=PATTERN ...] (--config=FILE | [--branch=BRANCH ...] URL | --worktree=PATH)
+  fromage move  [--verbose] [--dry-run] [--output=OUTPUT] (--from=FROM_REPOSITORY --to=TO_REPOSITORY | --deprecated [--deprecations=FILE]) [--path=PATTERN ...] (--branch=BRANCH URL | --worktree=PATH)
+  fromage revert [--verbose] [--dry-run] [--output=OUTPUT] [--path=PATTERN ...] (--branch=BRANCH URL | --worktree=PATH)
+  fromage history [--verbose] [--format=FORMAT] [--no-header] ([--branch=BRANCH ...] URL | --worktree=PATH)
 
 Options:
 --branch=BRANCH        to inspect, defaults to all branches.
---format=FORMAT        to print: text, json or yaml [default: text].
+--ref-pattern=PATTERN  branches, tags like refs/tags/v* or commit hashes to inspect.
+--path=PATTERN         of the Dockerfiles to read and update, like services/payments/**.
+--format=FORMAT        to print: text, json, yaml, sarif, junit, markdown or template [default: text].
+--template=TEMPLATE    Go template to print the references with, if the format is template.
+--template-file=FILE   file with the Go template to print the references with.
 --no-header            do not print header if output type is text.
 --only-references      output only container image references.
 --pin=LEVEL            pins the MAJOR or MINOR version level
+--pin-variant=LEVEL    pins the MAJOR or MINOR version level of a versioned variant, like alpine3.18.
 --latest               bump to the latest version available
+--pin-floating         replace floating tags, like latest, with the version they currently refer to.
+--only-final-stage     only bump the image on which the final stage is based.
+--skip-builder-stages  do not bump images only used in builder stages.
+--fail-on=LEVELS       only fail on newer MAJOR, MINOR and/or PATCH versions, like minor,patch.
+--max-age=MAX_AGE      only fail on images older than MAX_AGE, like 90d.
+--eol-data=FILE        end-of-life data in the endoflife.date format, defaults to the built-in snapshot.
+--fail-on-eol          fail on images which are past their end-of-life.
+--deprecations=FILE    rules for deprecated images, defaults to the built-in rules.
+--deprecated           replace deprecated images with their recommended replacement.
+--platforms=PLATFORMS  required platforms of the image, like linux/amd64,linux/arm64.
 --from=FROM_REPOSITORY from repository context
 --to=TO_REPOSITORY     to repository context
+--worktree=PATH        update the files in the directory in place, without commit.
+--output=OUTPUT        print the changes made: patch or json.
+--policy=FILE          policy to which the image references must comply.
+--listen=ADDRESS       address on which to serve the webhooks or metrics [default: :8080].
+--status=PROVIDER      post the result of every check as commit status to github or gitlab.
+--api-url=URL          of the GitHub or GitLab API, defaults to https://api.github.com or https://gitlab.com.
+--config=FILE          repositories to scan by the daemon or who-uses.
+--match=MODE           to match the image with: exact, prefix or regex [default: exact].
+--digest               also match references which resolve to the same digest as the image.
+--interval=INTERVAL    between the scans of the daemon, like 30m or 6h [default: 6h].
+--cache-dir=DIR        directory with the mirrors of the repositories and the results of previous scans.
 
 Description:
 list will iterate over all dockerfiles in all branches in the repository and print out all container
 image references and list newer versions if available.
 
-check will do the same, and if there are newer versions available print the out of date container
-image references and exit with 1.
+check will do the same, and if there are newer versions available print the out of date container image
+references and exit with 1. With --format=sarif, the out of date references are written in the SARIF
+format for code scanning tools. Every reference is classified by the most significant version level on
+which a newer version is available: major, minor or patch. With --fail-on, check only reports references
+for which a newer version is available on any of the specified levels. With --format=junit, every
+reference is a test case which fails if it is out of date. With --format=markdown, the references are
+written as tables per branch and Dockerfile. With --format=template, the references are printed with the
+Go template specified by --template or --template-file. The template is rendered over the list of
+references, and can use the functions latest, join and semverLevel, for example:
+
+  {{range .}}{{.Reference}} -> {{latest .Newer}} ({{semverLevel .Reference (latest .Newer)}}){{"\n"}}{{end}}
+
+With --platforms, only the next version and the latest version on each level are listed, if they provide
+all the required platforms, and references missing a required platform are reported. The platform
+specified with --platform on the FROM statement is always required.
+
+Tags with a versioned distribution as variant, like golang:1.21-alpine3.18, are composite versions: both
+the version and the version of the variant can be advanced, to 1.21-alpine3.19 or 1.22-alpine3.19, but the
+variant is never downgraded. Other versioned suffixes, like -jdk11, are never changed. With --pin-variant,
+the version of the variant is pinned like --pin pins the version: --pin-variant=minor only advances the
+version, and pinning the version on its last level, like --pin=minor for 1.21, only advances the variant.
+An update of only the variant is classified as variant, and check reports it with --fail-on=patch.
+
+The age of every image is determined from the creation timestamp in its image configuration, together
+with the number of days it is behind the newest version. With --max-age, check only reports out of date
+references with an image older than MAX_AGE, in days (90d), weeks (12w) or hours (2160h).
+
+The EOL column shows the end-of-life of the release cycle of the image, like python 3.7 or debian
+buster, from the built-in snapshot of https://endoflife.date or the file specified by --eol-data.
+With --fail-on-eol, list and check exit with 1 if any image is past its end-of-life, and check reports
+these images even if no newer version is available within the pinned level.
+
+lint will evaluate all container image references against the policy in the file specified by --policy,
+print the references which violate it and exit with 1. The policy specifies the allowed registries,
+the forbidden tags, whether untagged references are forbidden, whether a digest is required and the
+version level on which the tags must be pinned, for example:
+
+  allowed-registries: [harbor.corp, cgr.dev/chainguard]
+  forbidden-tags: [latest, edge]
+  forbid-untagged: true
+  require-digest: false
+  required-pin: minor
 
 bump will update the container images references on the specified branch and commit/push the changes
-back to the repository.
+back to the repository. With --pin-floating, references without a tag or with a floating tag, like latest
+or edge, are first replaced by the versioned tag which currently refers to the same image.
+
+With --only-final-stage, only the image on which the final stage is based is bumped. With
+--skip-builder-stages, images which are only used in builder stages are not bumped. A builder stage is a
+stage which is not part of the final image, but from which other stages copy files or on which they are based.
+The FINAL column of list and check shows yes for the image on which the final stage is based, and builder
+for images only used in builder stages.
+
+Floating references are reported by list and check, with the version they currently refer to, determined
+by comparing the digest of the image with the digests of the versioned tags.
+
+serve will listen for GitHub and GitLab push webhooks on /webhook, and check the container image
+references in every pushed commit like check does. The webhooks are verified with the secret in the
+environment variable FROMAGE_WEBHOOK_SECRET: GitHub webhooks by their HMAC-SHA256 signature, GitLab
+webhooks by their secret token. The latest result of every branch is served as JSON on /results. With
+--status, the result is posted as commit status, using the token in FROMAGE_STATUS_TOKEN.
+
+daemon will scan the repositories listed in the file specified by --config every interval, and expose
+the results as Prometheus metrics on /metrics. The references are checked like check does, for example:
+
+  repositories:
+    - url: https://github.com/binxio/kritis
+      branches: [master]
+
+With --cache-dir, list, check, serve and daemon keep a mirror of every repository in the directory. The
+first run fetches the branches into the mirror, later runs only fetch the new commits. list, check and
+daemon record the references found on every branch in the directory, and reuse them as long as the tip of
+the branch and the options, end-of-life data and deprecation rules do not change, for at most a day.
+
+With --ref-pattern, list and check only inspect the branches, tags and commits matching the pattern, like
+refs/tags/v* for all release tags, or an abbreviated commit hash, and the branches specified by --branch.
+A pattern matches the full or short name of a branch or tag. The REF TYPE column shows whether the
+Dockerfile was found on a branch, tag or commit. bump, move and revert only change branches, and refuse a
+tag specified by --branch.
+
+With --path, only the Dockerfiles matching any of the patterns are read and updated, like
+services/payments/** for all Dockerfiles below services/payments. In a pattern, ** matches any number of
+directories, and a pattern matching a directory selects all Dockerfiles below it. Directories which
+cannot contain a matching Dockerfile are skipped. The branches are still fetched completely, as partial
+clones are not supported.
+
+who-uses will list the references to the image IMAGE in the repository, or in all repositories listed
+in the file specified by --config, and exit with 1 if there are none. With --match=prefix, references
+starting with IMAGE match, like alpine:3.17 for alpine:3. With --match=regex, IMAGE is a regular
+expression. Both are matched against the reference as written and the fully qualified reference. With
+--digest, references which resolve to the same digest as IMAGE match too, like alpine@sha256:... for
+alpine:3.17.
 
 move will move the container image reference on the specified branch from one registry to another. The
-changes are committed/pushed back to the git repository.
+changes are committed/pushed back to the git repository. With --deprecated, move replaces deprecated
+images with their recommended replacement instead, like openjdk with eclipse-temurin.
+
+Deprecated images are reported by list and check, using the built-in rules or the rules in the file
+specified by --deprecations.
+
+revert will undo the most recent bump, move or replacement of deprecated images by fromage on the specified
+branch. The commit is found by its author or by its Fromage- trailers, and a new commit is made which restores
+the previous references at the position of each FROM statement. Only the FROM statements are changed, so
+later changes to the Dockerfiles are kept.
+References which have been changed again since are not reverted.
+
+The commits made by bump, move and revert record every changed reference in a Fromage-Change trailer,
+like "Fromage-Change: deploy/Dockerfile golang:1.12 -> golang:1.13", together with the type of the
+changes, the pinned levels and the version of fromage. history will read these trailers from the commit log of the branches, and print
+the timeline of the changes as text or json.
+
+With --output=patch, bump, move and revert print the changes as a unified diff, which can be applied with
+git apply. With --output=json, they print the list of changes, with the file, line, old reference,
+new reference and reason of each change. Combine it with --dry-run to review the changes first.
+
+The Dockerfiles are read from the git trees of the branches, which are never checked out. bump, move and
+revert commit their changes on the branch. If the branch is checked out in a local repository, its files
+are updated too, and the commit is refused if they have uncommitted changes.
+
+With --worktree, the Dockerfiles in the directory are read and updated in place. No branches are
+checked out and changes are not staged or committed. The directory does not need to be a git repository.
 `
 	var fromage Fromage
+	var ctx = context.Background()
 
 	if opts, err := docopt.ParseDoc(usage); err == nil {
 		if err = opts.Bind(&fromage); err != nil {
@@ -357,39 +501,153 @@ changes are committed/pushed back to the git repository.
 				fromage.pin = &limit
 			}
 		}
+		if fromage.Listen == "" {
+			fromage.Listen = ":8080"
+		}
+		if fromage.Interval == "" {
+			fromage.Interval = "6h"
+		}
+		if fromage.PinVariant != "" {
+			if limit, err := tag.MakeLevelFromString(fromage.PinVariant); err != nil {
+				log.Fatal(err)
+			} else {
+				fromage.variantPin = &limit
+			}
+		}
+		if fromage.Platforms != "" {
+			if fromage.platforms, err = tag.ParsePlatforms(fromage.Platforms); err != nil {
+				log.Fatal(err)
+			}
+		}
+		if fromage.paths, err = repository.NewPathFilter(fromage.Path); err != nil {
+			log.Fatal(err)
+		}
+		if fromage.FailOn != "" {
+			for _, level := range strings.Split(fromage.FailOn, ",") {
+				if l, err := tag.MakeLevelFromString(strings.TrimSpace(level)); err != nil {
+					log.Fatal(err)
+				} else {
+					fromage.failOn = append(fromage.failOn, l)
+				}
+			}
+		}
+		if fromage.EolData != "" {
+			if fromage.endOfLife, err = eol.Load(fromage.EolData); err != nil {
+				log.Fatal(err)
+			}
+		} else {
+			fromage.endOfLife = eol.Snapshot()
+		}
+		if fromage.Deprecations != "" {
+			if fromage.deprecations, err = deprecation.Load(fromage.Deprecations); err != nil {
+				log.Fatal(err)
+			}
+		} else {
+			fromage.deprecations = deprecation.Builtin()
+		}
+		if fromage.MaxAge != "" {
+			if fromage.maxAge, err = scan.ParseAge(fromage.MaxAge); err != nil {
+				log.Fatal(err)
+			}
+		}
+		if err = fromage.ParseTemplate(); err != nil {
+			log.Fatal(err)
+		}
+		if fromage.CacheDir != "" {
+			if fromage.state, err = scan.LoadState(filepath.Join(fromage.CacheDir, "state.json")); err != nil {
+				log.Fatal(err)
+			}
+		}
 	} else {
 		log.Fatal(err)
 	}
 
-	fromage.OpenRepository()
+	if fromage.Serve {
+		if err := fromage.ServeWebhooks(ctx); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	if fromage.WhoUses {
+		references, err := fromage.FindUsers(ctx)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err = fromage.OutputReferences(references); err != nil {
+			log.Fatal(err)
+		}
+		if len(references) == 0 {
+			os.Exit(1)
+		}
+		return
+	}
+	if fromage.Daemon {
+		if err := fromage.RunDaemon(ctx); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	fromage.OpenRepository(ctx)
 
 	if fromage.List || fromage.Check {
-		if err := fromage.ForEachDockerfile(ListAllReferences); err != nil {
+		references, err := scan.ListReferences(ctx, fromage.repository, fromage.ScanOptions())
+		if err != nil {
 			log.Fatal(err)
 		}
 
+		endOfLife := references.FilterEndOfLife()
 		if fromage.Check {
-			fromage.references = fromage.references.FilterOutOfDate()
+			references = references.Check(fromage.ScanOptions())
 		}
 
-		if fromage.OnlyReferences {
-			fromage.references.OutputOnlyReferences(fromage.Format, fromage.NoHeader)
-		} else {
-			fromage.references.Output(fromage.Format, fromage.NoHeader)
+		if err = fromage.OutputReferences(references); err != nil {
+			log.Fatal(err)
 		}
 
-		if fromage.Check && len(fromage.references) > 0 {
+		if fromage.Check && len(references) > 0 {
+			os.Exit(1)
+		}
+		if fromage.FailOnEol && len(endOfLife) > 0 {
+			os.Exit(1)
+		}
+	} else if fromage.Lint {
+		p, err := policy.Load(fromage.Policy)
+		if err != nil {
+			log.Fatal(err)
+		}
+		references, err := scan.LintReferences(ctx, fromage.repository, fromage.Branch, p)
+		if err != nil {
+			log.Fatal(err)
+		}
+		references = references.FilterViolations()
+		if err = fromage.OutputReferences(references); err != nil {
+			log.Fatal(err)
+		}
+		if len(references) > 0 {
 			os.Exit(1)
 		}
 	} else if fromage.Bump {
-		if err := fromage.ForEachDockerfile(BumpReferences); err != nil {
+		result, err := bump.Update(ctx, fromage.repository, fromage.BumpOptions())
+		if err != nil {
 			log.Fatal(err)
 		}
 		msg := "container image references bumped"
 		if fromage.pin != nil {
 			msg = msg + " pinned on " + strings.ToLower(fromage.pin.String()) + " level"
 		}
-		if err := fromage.CommitAndPush(msg); err != nil {
+		if fromage.variantPin != nil {
+			msg = msg + ", variant pinned on " + strings.ToLower(fromage.variantPin.String()) + " level"
+		}
+		if err := fromage.CommitAndPush(ctx, result, msg); err != nil {
+			log.Fatal(err)
+		}
+	} else if fromage.Move && fromage.Deprecated {
+		result, err := bump.ReplaceDeprecated(ctx, fromage.repository, fromage.deprecations, fromage.BumpOptions())
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := fromage.CommitAndPush(ctx, result, "replaced deprecated container image references"); err != nil {
 			log.Fatal(err)
 		}
 	} else if fromage.Move {
@@ -399,56 +657,38 @@ changes are committed/pushed back to the git repository.
 		if strings.ContainsAny(fromage.From, "@:") || strings.ContainsAny(fromage.To, "@:") {
 			log.Fatal("the --from and --to image references should not contain an tag or digest")
 		}
-		if err := fromage.ForEachDockerfile(MoveImageReferences); err != nil {
+		result, err := bump.Move(ctx, fromage.repository, fromage.From, fromage.To, fromage.BumpOptions())
+		if err != nil {
 			log.Fatal(err)
 		}
-		if err := fromage.CommitAndPush(fmt.Sprintf("moved references from %s to %s", fromage.From, fromage.To)); err != nil {
+		if err := fromage.CommitAndPush(ctx, result, fmt.Sprintf("moved references from %s to %s", fromage.From, fromage.To)); err != nil {
 			log.Fatal(err)
 		}
-	} else {
-		log.Fatalf("I don't know what to do")
-	}
-}
-
-func (f *Fromage) CommitAndPush(msg string) error {
-	if !f.updated {
-		return nil
-	}
-	log.Printf("INFO: %s", msg)
-	if !f.DryRun {
-		hash, err := f.workTree.Commit(msg, &git.CommitOptions{
-			Author: &object.Signature{
-				Name:  "fromage",
-				Email: "fromage@binx.io",
-				When:  time.Now(),
-			},
-		})
+	} else if fromage.History {
+		entries, err := history.Read(ctx, fromage.repository, fromage.Branch)
 		if err != nil {
-			return err
+			log.Fatal(err)
 		}
-		log.Printf("INFO: changes committed with %s", hash.String()[0:7])
-	} else {
-		log.Printf("INFO: changes would be committed")
-	}
-
-	if f.IsLocalRepository() {
-		return nil
-	}
-
-	if !f.DryRun {
-		var progress io.Writer = os.Stderr
-		if !f.Verbose {
-			progress = &bytes.Buffer{}
+		if err = entries.Output(os.Stdout, fromage.Format, fromage.NoHeader); err != nil {
+			log.Fatal(err)
 		}
-		log.Printf("INFO: pushing changes to %s", f.Url)
-
-		auth, _, err := GetAuth(f.Url)
+	} else if fromage.Revert {
+		branch := ""
+		if len(fromage.Branch) > 0 {
+			branch = fromage.Branch[0]
+		}
+		commit, err := bump.FindRevertable(ctx, fromage.repository, branch)
 		if err != nil {
-			return err
+			log.Fatal(err)
+		}
+		result, err := bump.Revert(ctx, fromage.repository, commit, fromage.BumpOptions())
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := fromage.CommitAndPush(ctx, result, bump.RevertMessage(commit)); err != nil {
+			log.Fatal(err)
 		}
-		return f.repository.Push(&git.PushOptions{Auth: auth, Progress: progress})
 	} else {
-		log.Printf("INFO: changes would be pushed to %s", f.Url)
+		log.Fatalf("I don't know what to do")
 	}
-	return nil
 }
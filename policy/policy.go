@@ -0,0 +1,151 @@
+// Package policy evaluates container image references against the rules of a policy, like the registries
+// from which images may be pulled.
+package policy
+
+import (
+	"fmt"
+	"github.com/binxio/fromage/tag"
+	"github.com/google/go-containerregistry/pkg/name"
+	"gopkg.in/yaml.v3"
+	"io/ioutil"
+	"path"
+	"strings"
+)
+
+const (
+	DisallowedRegistry = "disallowed-registry"
+	ForbiddenTag       = "forbidden-tag"
+	Untagged           = "untagged"
+	MissingDigest      = "missing-digest"
+	InsufficientPin    = "insufficient-pin"
+)
+
+// Policy are the rules to which the container image references must comply. AllowedRegistries are the
+// registries or repository prefixes from which images may be used, like harbor.corp or cgr.dev/chainguard.
+// ForbiddenTags are the tags, or tag patterns like *-rc*, which may not be used.
+type Policy struct {
+	AllowedRegistries []string `yaml:"allowed-registries,omitempty"`
+	ForbiddenTags     []string `yaml:"forbidden-tags,omitempty"`
+	ForbidUntagged    bool     `yaml:"forbid-untagged,omitempty"`
+	RequireDigest     bool     `yaml:"require-digest,omitempty"`
+	RequiredPin       string   `yaml:"required-pin,omitempty"`
+
+	prefixes    []string
+	requiredPin *tag.Level
+}
+
+// Violation is a reference not complying to a rule of the policy.
+type Violation struct {
+	Rule    string `json:"rule" yaml:"rule"`
+	Message string `json:"message" yaml:"message"`
+}
+
+func (v Violation) String() string {
+	return v.Message
+}
+
+// normalize returns the fully qualified prefix of a registry or repository, ending with a slash.
+func normalize(prefix string) (string, error) {
+	parts := strings.SplitN(strings.TrimSuffix(prefix, "/"), "/", 2)
+	registry, err := name.NewRegistry(parts[0])
+	if err != nil {
+		return "", err
+	}
+	if len(parts) == 1 {
+		return registry.Name() + "/", nil
+	}
+	return registry.Name() + "/" + parts[1] + "/", nil
+}
+
+// Parse reads the policy from the YAML content.
+func Parse(content []byte) (*Policy, error) {
+	var result Policy
+	if err := yaml.Unmarshal(content, &result); err != nil {
+		return nil, fmt.Errorf("could not read policy, %s", err)
+	}
+	for _, registry := range result.AllowedRegistries {
+		prefix, err := normalize(registry)
+		if err != nil {
+			return nil, fmt.Errorf("%s is not a valid registry, %s", registry, err)
+		}
+		result.prefixes = append(result.prefixes, prefix)
+	}
+	for _, pattern := range result.ForbiddenTags {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("%s is not a valid tag pattern, %s", pattern, err)
+		}
+	}
+	if result.RequiredPin != "" {
+		level, err := tag.MakeLevelFromString(result.RequiredPin)
+		if err != nil {
+			return nil, err
+		}
+		result.requiredPin = &level
+	}
+	return &result, nil
+}
+
+// Load reads the policy from the file.
+func Load(filename string) (*Policy, error) {
+	content, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(content)
+}
+
+// isUntagged returns true if the reference has neither a tag nor a digest.
+func isUntagged(reference string) bool {
+	return !strings.Contains(reference, "@") &&
+		strings.LastIndex(reference, ":") < strings.LastIndex(reference, "/")+1
+}
+
+// Evaluate returns the rules of the policy with which the reference does not comply. The scratch image is
+// not pulled from a registry, so it complies with every rule.
+func (p *Policy) Evaluate(reference string) []Violation {
+	ref, err := name.ParseReference(reference)
+	if err != nil {
+		return []Violation{{Rule: DisallowedRegistry, Message: fmt.Sprintf("%s is not a valid reference, %s", reference, err)}}
+	}
+	result := make([]Violation, 0)
+	if tag.IsScratch(ref) {
+		return result
+	}
+
+	if len(p.prefixes) > 0 {
+		allowed := false
+		for _, prefix := range p.prefixes {
+			allowed = allowed || strings.HasPrefix(ref.Context().Name()+"/", prefix)
+		}
+		if !allowed {
+			result = append(result, Violation{DisallowedRegistry,
+				fmt.Sprintf("%s is not from an allowed registry: %s", reference, strings.Join(p.AllowedRegistries, ", "))})
+		}
+	}
+
+	_, digest := ref.(name.Digest)
+	untagged := isUntagged(reference)
+	if untagged && p.ForbidUntagged {
+		result = append(result, Violation{Untagged, fmt.Sprintf("%s has no tag", reference)})
+	}
+
+	if t, ok := ref.(name.Tag); ok {
+		for _, pattern := range p.ForbiddenTags {
+			if matched, _ := path.Match(pattern, t.TagStr()); matched {
+				result = append(result, Violation{ForbiddenTag, fmt.Sprintf("%s uses the forbidden tag %s", reference, t.TagStr())})
+				break
+			}
+		}
+		if p.requiredPin != nil && !untagged {
+			if version := tag.MakeTag(t.TagStr()).Version; len(version) <= int(*p.requiredPin) {
+				result = append(result, Violation{InsufficientPin,
+					fmt.Sprintf("%s is not pinned on %s level", reference, strings.ToLower(p.requiredPin.String()))})
+			}
+		}
+	}
+
+	if p.RequireDigest && !digest {
+		result = append(result, Violation{MissingDigest, fmt.Sprintf("%s is not pinned by digest", reference)})
+	}
+	return result
+}
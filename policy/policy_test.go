@@ -0,0 +1,77 @@
+package policy
+
+import (
+	"testing"
+)
+
+func TestEvaluate(t *testing.T) {
+	policy, err := Parse([]byte(`
+allowed-registries:
+  - harbor.corp
+  - cgr.dev/chainguard/
+forbidden-tags:
+  - latest
+  - "*-rc*"
+forbid-untagged: true
+required-pin: minor
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var tests = []struct {
+		reference string
+		rules     []string
+	}{
+		{"harbor.corp/base/python:3.12", nil},
+		{"cgr.dev/chainguard/node:20.11", nil},
+		{"cgr.dev/chainguard-private/node:20.11", []string{DisallowedRegistry}},
+		{"python:3.12", []string{DisallowedRegistry}},
+		{"scratch", nil},
+		{"harbor.corp/base/python", []string{Untagged, ForbiddenTag}},
+		{"harbor.corp/base/python:latest", []string{ForbiddenTag, InsufficientPin}},
+		{"harbor.corp/base/python:3.13-rc1", []string{ForbiddenTag}},
+		{"harbor.corp/base/python:3", []string{InsufficientPin}},
+		{"localhost:5000/python", []string{DisallowedRegistry, Untagged, ForbiddenTag}},
+	}
+	for _, test := range tests {
+		violations := policy.Evaluate(test.reference)
+		if len(violations) != len(test.rules) {
+			t.Fatalf("expected %v for %s, got %v", test.rules, test.reference, violations)
+		}
+		for i, violation := range violations {
+			if violation.Rule != test.rules[i] {
+				t.Fatalf("expected %v for %s, got %v", test.rules, test.reference, violations)
+			}
+		}
+	}
+}
+
+func TestRequireDigest(t *testing.T) {
+	policy, err := Parse([]byte("require-digest: true\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if violations := policy.Evaluate("python:3.12"); len(violations) != 1 || violations[0].Rule != MissingDigest {
+		t.Fatalf("expected %s, got %v", MissingDigest, violations)
+	}
+	if violations := policy.Evaluate("scratch"); len(violations) != 0 {
+		t.Fatalf("expected no violations for scratch, got %v", violations)
+	}
+	digest := "python@sha256:3ab1b26d7a1aad6b6d8a7e8d1e5b7b7e1a9f0a1c2e1c0d7e0f8e9a0b1c2d3e4f"
+	if violations := policy.Evaluate(digest); len(violations) != 0 {
+		t.Fatalf("expected no violations for %s, got %v", digest, violations)
+	}
+}
+
+func TestParse(t *testing.T) {
+	for _, content := range []string{
+		"required-pin: build\n",
+		"forbidden-tags: ['[']\n",
+		"allowed-registries: ['Harbor Corp']\n",
+	} {
+		if _, err := Parse([]byte(content)); err == nil {
+			t.Fatalf("expected an error for %q", content)
+		}
+	}
+}
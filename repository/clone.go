@@ -1,11 +1,11 @@
-package main
+package repository
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	sshconfig "github.com/kevinburke/ssh_config"
 	"golang.org/x/crypto/ssh"
-	"gopkg.in/src-d/go-billy.v4/memfs"
 	"gopkg.in/src-d/go-git.v4"
 	"gopkg.in/src-d/go-git.v4/config"
 	"gopkg.in/src-d/go-git.v4/plumbing/transport"
@@ -21,46 +21,50 @@ import (
 	"strings"
 )
 
-func getCredentialHelper(url string) string {
-	cmd := exec.Command("git", "config", "--get-urlmatch", "credential.helper", url)
+func getCredentialHelper(ctx context.Context, url string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", "config", "--get-urlmatch", "credential.helper", url)
 	helper, err := cmd.Output()
 	if err == nil {
-		return strings.TrimSpace(string(helper))
+		return strings.TrimSpace(string(helper)), nil
 	}
 
 	if exiterr, ok := err.(*exec.ExitError); ok {
 		if exiterr.ExitCode() != 1 {
-			log.Fatalf("ERROR: %s returned exitcode %d", cmd.String(), exiterr.ExitCode())
+			return "", fmt.Errorf("%s returned exitcode %d", cmd.String(), exiterr.ExitCode())
 		}
 	} else {
-		log.Fatalf("ERROR: %s failed %s", cmd.String(), err)
+		return "", fmt.Errorf("%s failed %s", cmd.String(), err)
 	}
-	return ""
+	return "", nil
 }
 
-func getPassword(repositoryUrl string) transport.AuthMethod {
+func getPassword(ctx context.Context, repositoryUrl string) (transport.AuthMethod, error) {
 
 	u, err := url.Parse(repositoryUrl)
 	if err != nil {
-		log.Fatalf("ERROR: url '%s' could not be parsed, %s", repositoryUrl, err)
+		return nil, fmt.Errorf("url '%s' could not be parsed, %s", repositoryUrl, err)
 	}
 
-	if os.Getenv("GIT_ASKPASS") == "" && getCredentialHelper(repositoryUrl) == "" {
+	helper, err := getCredentialHelper(ctx, repositoryUrl)
+	if err != nil {
+		return nil, err
+	}
+	if os.Getenv("GIT_ASKPASS") == "" && helper == "" {
 		// No credential helper specified, not passing in credentials
-		return nil
+		return nil, nil
 	}
 
 	user := u.User.Username()
 	password, _ := u.User.Password()
 
-	cmd := exec.Command("git", "credential", "fill")
+	cmd := exec.CommandContext(ctx, "git", "credential", "fill")
 	stdin, err := cmd.StdinPipe()
 	if err != nil {
-		log.Fatalf("ERROR: internal error on getPassword %s", err)
+		return nil, fmt.Errorf("internal error on getPassword %s", err)
 	}
 	stderr, err := cmd.StderrPipe()
 	if err != nil {
-		log.Fatalf("ERROR: internal error on getPassword %s", err)
+		return nil, fmt.Errorf("internal error on getPassword %s", err)
 	}
 
 	go func() {
@@ -72,7 +76,7 @@ func getPassword(repositoryUrl string) transport.AuthMethod {
 	out, err := cmd.Output()
 	if err != nil {
 		io.Copy(os.Stderr, stderr)
-		log.Fatalf("ERROR: git credential fill failed, %s", err)
+		return nil, fmt.Errorf("git credential fill failed, %s", err)
 	}
 
 	for _, line := range strings.Split(string(out), "\n") {
@@ -85,7 +89,7 @@ func getPassword(repositoryUrl string) transport.AuthMethod {
 		}
 	}
 
-	return &githttp.BasicAuth{Username: user, Password: password}
+	return &githttp.BasicAuth{Username: user, Password: password}, nil
 }
 
 func identityFileAuthentication(user string, host string) (auth transport.AuthMethod, err error) {
@@ -118,7 +122,7 @@ func identityFileAuthentication(user string, host string) (auth transport.AuthMe
 		return sshAgentAuthentication(user, host, keyFile, publicKey)
 	}
 
-	return nil, fmt.Errorf("ERROR: failed to read private key from '%s', %s", keyFile, parseError)
+	return nil, fmt.Errorf("failed to read private key from '%s', %s", keyFile, parseError)
 }
 
 func sshAgentAuthentication(user, host, keyFile string, key ssh.PublicKey) (auth transport.AuthMethod, err error) {
@@ -133,12 +137,12 @@ func sshAgentAuthentication(user, host, keyFile string, key ssh.PublicKey) (auth
 
 	publicKeys, err := gitssh.NewSSHAgentAuth(user)
 	if err != nil {
-		return nil, fmt.Errorf("ERROR: failed to connect ssh agent, %s", err)
+		return nil, fmt.Errorf("failed to connect ssh agent, %s", err)
 	}
 
 	signers, err := publicKeys.Callback()
 	if err != nil {
-		return nil, fmt.Errorf("ERROR: failed to obtain keys from ssh agent, %s", err)
+		return nil, fmt.Errorf("failed to obtain keys from ssh agent, %s", err)
 	}
 	for _, signer := range signers {
 		if bytes.Compare(signer.PublicKey().Marshal(), key.Marshal()) == 0 {
@@ -152,13 +156,11 @@ func sshAgentAuthentication(user, host, keyFile string, key ssh.PublicKey) (auth
 	return nil, nil
 }
 
-func GetAuth(url string) (auth transport.AuthMethod, plainOpen bool, err error) {
+func GetAuth(ctx context.Context, url string) (auth transport.AuthMethod, plainOpen bool, err error) {
 
 	if MatchesScheme(url) {
-		if os.Getenv("GIT_ASKPASS") != "" || getCredentialHelper(url) != "" {
-			auth = getPassword(url)
-		}
-		return auth, false, nil
+		auth, err = getPassword(ctx, url)
+		return auth, false, err
 	}
 
 	if MatchesScpLike(url) {
@@ -175,11 +177,11 @@ func GetAuth(url string) (auth transport.AuthMethod, plainOpen bool, err error)
 	return
 }
 
-func Clone(url string, progress io.Writer, readOnly bool) (r *git.Repository, err error) {
+func Clone(ctx context.Context, url string, progress io.Writer, readOnly bool) (r *git.Repository, err error) {
 	var plainOpen bool
 	var auth transport.AuthMethod
 
-	if auth, plainOpen, err = GetAuth(url); err != nil {
+	if auth, plainOpen, err = GetAuth(ctx, url); err != nil {
 		return nil, err
 	}
 
@@ -190,14 +192,17 @@ func Clone(url string, progress io.Writer, readOnly bool) (r *git.Repository, er
 				return nil, err
 			}
 		} else {
-			r, err = git.Clone(memory.NewStorage(), memfs.New(), &git.CloneOptions{
+			r, err = git.CloneContext(ctx, memory.NewStorage(), nil, &git.CloneOptions{
 				URL:      url,
 				Progress: progress,
 				Depth:    2,
 			})
+			if err != nil {
+				return nil, err
+			}
 		}
 	} else {
-		r, err = git.Clone(memory.NewStorage(), memfs.New(), &git.CloneOptions{
+		r, err = git.CloneContext(ctx, memory.NewStorage(), nil, &git.CloneOptions{
 			URL:      url,
 			Progress: progress,
 			Auth:     auth,
@@ -207,13 +212,13 @@ func Clone(url string, progress io.Writer, readOnly bool) (r *git.Repository, er
 		if err != nil {
 			return nil, err
 		}
-		err = r.Fetch(&git.FetchOptions{
+		err = r.FetchContext(ctx, &git.FetchOptions{
 			RefSpecs: []config.RefSpec{"refs/*:refs/*"},
 			Depth:    1,
 			Auth:     auth,
 		})
 		if err != nil && err != git.NoErrAlreadyUpToDate {
-			return nil, fmt.Errorf("ERROR: failed to fetch all branches from %s, %s", url, err)
+			return nil, fmt.Errorf("failed to fetch all branches from %s, %s", url, err)
 		}
 	}
 
@@ -0,0 +1,191 @@
+package repository
+
+import (
+	"fmt"
+	"gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+	"strings"
+	"time"
+)
+
+// branchChanges are the Dockerfiles written on a branch, by path, and the commit on which they are based.
+type branchChanges struct {
+	base  plumbing.Hash
+	files map[string][]byte
+}
+
+// commitChanges creates a commit with the changes on top of their base commit, and moves the branch to it.
+// The branch is only moved if it still refers to the base commit. If the branch is checked out, the checked
+// out files are updated too, which is refused when they have uncommitted changes.
+func (r *Repository) commitChanges(branch plumbing.ReferenceName, changes *branchChanges, msg string) (plumbing.Hash, error) {
+	worktree, err := r.checkedOut(branch)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	parent, err := r.repository.CommitObject(changes.base)
+	if err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("failed to read the commit of %s, %s", branch.Short(), err)
+	}
+	tree, err := parent.Tree()
+	if err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("failed to read the tree of %s, %s", branch.Short(), err)
+	}
+	treeHash, err := r.updateTree(tree, "", changes.files)
+	if err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("failed to update the tree of %s, %s", branch.Short(), err)
+	}
+
+	signature := object.Signature{Name: AuthorName, Email: AuthorEmail, When: time.Now()}
+	hash, err := r.store(&object.Commit{
+		Author:       signature,
+		Committer:    signature,
+		Message:      msg,
+		TreeHash:     treeHash,
+		ParentHashes: []plumbing.Hash{changes.base},
+	})
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	err = r.repository.Storer.CheckAndSetReference(plumbing.NewHashReference(branch, hash),
+		plumbing.NewHashReference(branch, changes.base))
+	if err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("failed to move %s to %s, %s", branch.Short(), hash.String()[0:7], err)
+	}
+
+	if worktree != nil {
+		if err = checkoutFiles(worktree, changes.files); err != nil {
+			return plumbing.ZeroHash, fmt.Errorf("failed to check out %s, %s", hash.String()[0:7], err)
+		}
+	}
+	return hash, nil
+}
+
+// checkoutFiles writes the files to the worktree and stages them, so that they match the committed tree.
+func checkoutFiles(worktree *git.Worktree, files map[string][]byte) error {
+	for filename, content := range files {
+		file, err := worktree.Filesystem.Create(filename)
+		if err != nil {
+			return err
+		}
+		_, err = file.Write(content)
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return err
+		}
+		if _, err = worktree.Add(filename); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkedOut returns the worktree in which the branch is checked out, or nil if it is not checked out. It fails
+// if the checked out files have uncommitted changes, as they would be lost when checking out the commit.
+func (r *Repository) checkedOut(branch plumbing.ReferenceName) (*git.Worktree, error) {
+	head, err := r.repository.Head()
+	if err != nil || head.Name() != branch {
+		return nil, nil
+	}
+	worktree, err := r.repository.Worktree()
+	if err != nil {
+		return nil, nil
+	}
+	status, err := worktree.Status()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the status of %s, %s", r.Url, err)
+	}
+	for _, file := range status {
+		if file.Staging != git.Untracked || file.Worktree != git.Untracked {
+			return nil, fmt.Errorf("%s is checked out in %s with uncommitted changes, not committed",
+				branch.Short(), r.Url)
+		}
+	}
+	return worktree, nil
+}
+
+// updateTree stores the tree with the files below prefix replaced by their new content, and returns its hash.
+// Only existing files are replaced.
+func (r *Repository) updateTree(tree *object.Tree, prefix string, files map[string][]byte) (plumbing.Hash, error) {
+	entries := make([]object.TreeEntry, len(tree.Entries))
+	copy(entries, tree.Entries)
+
+	directories := make(map[string]bool)
+	for filename, content := range files {
+		if !strings.HasPrefix(filename, prefix) {
+			continue
+		}
+		name := strings.TrimPrefix(filename, prefix)
+		if i := strings.Index(name, "/"); i >= 0 {
+			directories[name[0:i]] = true
+			continue
+		}
+		entry := findEntry(entries, name)
+		if entry == nil || !entry.Mode.IsFile() {
+			return plumbing.ZeroHash, fmt.Errorf("%s is not a file", filename)
+		}
+		hash, err := r.storeBlob(content)
+		if err != nil {
+			return plumbing.ZeroHash, err
+		}
+		entry.Hash = hash
+	}
+
+	for directory := range directories {
+		entry := findEntry(entries, directory)
+		if entry == nil {
+			return plumbing.ZeroHash, fmt.Errorf("%s%s is not a directory", prefix, directory)
+		}
+		subtree, err := r.repository.TreeObject(entry.Hash)
+		if err != nil {
+			return plumbing.ZeroHash, fmt.Errorf("%s%s is not a directory, %s", prefix, directory, err)
+		}
+		if entry.Hash, err = r.updateTree(subtree, prefix+directory+"/", files); err != nil {
+			return plumbing.ZeroHash, err
+		}
+	}
+	return r.store(&object.Tree{Entries: entries})
+}
+
+func findEntry(entries []object.TreeEntry, name string) *object.TreeEntry {
+	for i := range entries {
+		if entries[i].Name == name {
+			return &entries[i]
+		}
+	}
+	return nil
+}
+
+// encodable is a git object which can be stored.
+type encodable interface {
+	Encode(o plumbing.EncodedObject) error
+}
+
+func (r *Repository) store(o encodable) (plumbing.Hash, error) {
+	obj := r.repository.Storer.NewEncodedObject()
+	if err := o.Encode(obj); err != nil {
+		return plumbing.ZeroHash, err
+	}
+	return r.repository.Storer.SetEncodedObject(obj)
+}
+
+func (r *Repository) storeBlob(content []byte) (plumbing.Hash, error) {
+	obj := r.repository.Storer.NewEncodedObject()
+	obj.SetType(plumbing.BlobObject)
+	w, err := obj.Writer()
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+	if _, err = w.Write(content); err != nil {
+		w.Close()
+		return plumbing.ZeroHash, err
+	}
+	if err = w.Close(); err != nil {
+		return plumbing.ZeroHash, err
+	}
+	return r.repository.Storer.SetEncodedObject(obj)
+}
@@ -0,0 +1,144 @@
+package repository
+
+import (
+	"context"
+	"gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCommitAndPush(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fromage-commit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	g, err := git.PlainInit(dir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = os.MkdirAll(filepath.Join(dir, "deploy"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	commitFile(t, g, dir, "Dockerfile", "FROM alpine:3.17\n")
+	commitFile(t, g, dir, "deploy/README.md", "deployment\n")
+	base := commitFile(t, g, dir, "deploy/Dockerfile", "FROM golang:1.12\n")
+	if err = ioutil.WriteFile(filepath.Join(dir, "notes.txt"), []byte("untracked\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := Open(context.Background(), dir, false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := make([]string, 0)
+	err = r.ForEachDockerfile(context.Background(), []string{"master"}, func(branch *plumbing.Reference, dockerfile string, content []byte) error {
+		found = append(found, dockerfile)
+		if dockerfile == "deploy/Dockerfile" {
+			return r.WriteFile(branch, dockerfile, []byte("FROM golang:1.13\n"))
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(found) != 2 || found[0] != "Dockerfile" || found[1] != "deploy/Dockerfile" {
+		t.Fatalf("expected Dockerfile and deploy/Dockerfile, got %v", found)
+	}
+	if err = r.CommitAndPush(context.Background(), "bumped", false); err != nil {
+		t.Fatal(err)
+	}
+
+	head, err := g.Reference(plumbing.NewBranchReferenceName("master"), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	commit, err := g.CommitObject(head.Hash())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(commit.ParentHashes) != 1 || commit.ParentHashes[0] != base || commit.Author.Email != AuthorEmail {
+		t.Fatalf("expected a commit by fromage on %s, got parents %v by %s", base, commit.ParentHashes, commit.Author.Email)
+	}
+	expect := map[string]string{
+		"Dockerfile":        "FROM alpine:3.17\n",
+		"deploy/README.md":  "deployment\n",
+		"deploy/Dockerfile": "FROM golang:1.13\n",
+	}
+	for name, content := range expect {
+		file, err := commit.File(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c, _ := file.Contents(); c != content {
+			t.Fatalf("expected %s to contain %q, got %q", name, content, c)
+		}
+	}
+
+	content, err := ioutil.ReadFile(filepath.Join(dir, "deploy", "Dockerfile"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "FROM golang:1.13\n" {
+		t.Fatalf("expected the checked out file to be updated, got %s", string(content))
+	}
+	worktree, err := g.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	status, err := worktree.Status()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(status) != 1 || !status.IsUntracked("notes.txt") {
+		t.Fatalf("expected the checked out files to match the commit, got\n%s", status)
+	}
+}
+
+func TestCommitAndPushRefusesUncommittedChanges(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fromage-commit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	g, err := git.PlainInit(dir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	base := commitFile(t, g, dir, "Dockerfile", "FROM golang:1.12\n")
+	if err = ioutil.WriteFile(filepath.Join(dir, "Dockerfile"), []byte("FROM golang:1.12\nRUN make\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := Open(context.Background(), dir, false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = r.ForEachDockerfile(context.Background(), []string{"master"}, func(branch *plumbing.Reference, dockerfile string, content []byte) error {
+		return r.WriteFile(branch, dockerfile, []byte("FROM golang:1.13\n"))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = r.CommitAndPush(context.Background(), "bumped", false); err == nil {
+		t.Fatalf("expected the commit to be refused, as master has uncommitted changes")
+	}
+
+	head, err := g.Reference(plumbing.NewBranchReferenceName("master"), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if head.Hash() != base {
+		t.Fatalf("expected master to be left at %s, got %s", base, head.Hash())
+	}
+	content, err := ioutil.ReadFile(filepath.Join(dir, "Dockerfile"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "FROM golang:1.12\nRUN make\n" {
+		t.Fatalf("expected the uncommitted changes to be kept, got %s", string(content))
+	}
+}
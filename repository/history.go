@@ -0,0 +1,136 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/config"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+	"io"
+	"strings"
+)
+
+const (
+	// AuthorName and AuthorEmail identify the author of the commits made by fromage.
+	AuthorName  = "fromage"
+	AuthorEmail = "fromage@binx.io"
+
+	// TrailerPrefix is the prefix of the keys of the git trailers added by fromage.
+	TrailerPrefix = "Fromage-"
+
+	// ChangeTrailer records a changed reference as `path from -> to`.
+	ChangeTrailer = TrailerPrefix + "Change"
+	// PinTrailer and VariantPinTrailer record the levels on which the bump was pinned.
+	PinTrailer        = TrailerPrefix + "Pin"
+	VariantPinTrailer = TrailerPrefix + "Pin-Variant"
+	// VersionTrailer records the version of fromage which made the commit.
+	VersionTrailer = TrailerPrefix + "Version"
+	// TypeTrailer records the type of the changes: bump, move, deprecation or revert.
+	TypeTrailer = TrailerPrefix + "Type"
+	// RevertTrailer records the commit reverted.
+	RevertTrailer = TrailerPrefix + "Revert"
+)
+
+// trailerLines returns the lines of the last paragraph of the commit message, in which git trailers reside.
+func trailerLines(message string) []string {
+	paragraphs := strings.Split(strings.TrimSpace(message), "\n\n")
+	return strings.Split(paragraphs[len(paragraphs)-1], "\n")
+}
+
+// Trailers returns the values of the git trailer with the key in the commit message.
+func Trailers(message string, key string) []string {
+	result := make([]string, 0)
+	for _, line := range trailerLines(message) {
+		parts := strings.SplitN(line, ":", 2)
+		if len(parts) == 2 && strings.EqualFold(strings.TrimSpace(parts[0]), key) {
+			result = append(result, strings.TrimSpace(parts[1]))
+		}
+	}
+	return result
+}
+
+// IsFromageCommit returns true if the commit was authored by fromage, or has a trailer added by fromage.
+func IsFromageCommit(commit *object.Commit) bool {
+	if commit.Author.Email == AuthorEmail {
+		return true
+	}
+	for _, line := range trailerLines(commit.Message) {
+		if strings.HasPrefix(line, TrailerPrefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Head returns the reference of the branch or, for a worktree, of the checked out commit.
+func (r *Repository) Head(branch string) (*plumbing.Reference, error) {
+	if r.repository == nil {
+		return nil, fmt.Errorf("%s is not a git repository", r.Url)
+	}
+	if r.inPlace {
+		if r.head.Hash().IsZero() {
+			return nil, fmt.Errorf("%s has no commits", r.Url)
+		}
+		return r.head, nil
+	}
+	ref, err := r.repository.Reference(plumbing.NewBranchReferenceName(branch), true)
+	if err != nil {
+		return nil, fmt.Errorf("branch %s not found in %s, %s", branch, r.Url, err)
+	}
+	return ref, nil
+}
+
+// Log calls m for every commit reachable from the reference, newest first, until m returns false. The log
+// ends at the boundary of a shallow clone.
+func (r *Repository) Log(ref *plumbing.Reference, m func(commit *object.Commit) (bool, error)) error {
+	iter, err := r.repository.Log(&git.LogOptions{From: ref.Hash()})
+	if err != nil {
+		return err
+	}
+	defer iter.Close()
+	for {
+		commit, err := iter.Next()
+		if err != nil {
+			if err == io.EOF || err == plumbing.ErrObjectNotFound {
+				return nil
+			}
+			return err
+		}
+		next, err := m(commit)
+		if err != nil || !next {
+			return err
+		}
+	}
+}
+
+// Deepen fetches the history of the branch up to depth commits, if the repository was cloned shallow.
+func (r *Repository) Deepen(ctx context.Context, branch string, depth int) error {
+	name := plumbing.NewBranchReferenceName(branch)
+	return r.deepen(ctx, []config.RefSpec{config.RefSpec(fmt.Sprintf("+%s:%s", name, name))}, depth)
+}
+
+// deepen fetches the history of the references up to depth commits, if the repository was cloned shallow.
+func (r *Repository) deepen(ctx context.Context, refSpecs []config.RefSpec, depth int) error {
+	if r.inPlace || !r.isShallow() {
+		return nil
+	}
+	auth, _, err := GetAuth(ctx, r.Url)
+	if err != nil {
+		return err
+	}
+	err = r.repository.FetchContext(ctx, &git.FetchOptions{
+		RefSpecs: refSpecs,
+		Depth:    depth,
+		Auth:     auth,
+	})
+	if err != nil && err != git.NoErrAlreadyUpToDate {
+		return fmt.Errorf("failed to fetch the history of %s from %s, %s", refSpecs, r.Url, err)
+	}
+	return nil
+}
+
+func (r *Repository) isShallow() bool {
+	shallow, err := r.repository.Storer.Shallow()
+	return err == nil && len(shallow) > 0
+}
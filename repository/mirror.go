@@ -0,0 +1,118 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"gopkg.in/src-d/go-billy.v4/osfs"
+	"gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/config"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/cache"
+	"gopkg.in/src-d/go-git.v4/storage/filesystem"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+)
+
+var unsafePathCharacters = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// MirrorPath returns the directory of the mirror of the repository at url in the cache directory.
+func MirrorPath(cacheDir string, url string) string {
+	sum := sha256.Sum256([]byte(url))
+	return filepath.Join(cacheDir, fmt.Sprintf("%s-%x", unsafePathCharacters.ReplaceAllString(url, "_"), sum[0:4]))
+}
+
+// OpenMirror opens the repository at url from its mirror in the cache directory. The first time, the
+// branches and tags are fetched into a new mirror; afterwards, only the new commits are fetched and branches
+// and tags which no longer exist are removed. The mirror is a bare repository.
+func OpenMirror(ctx context.Context, url string, cacheDir string, verbose bool) (*Repository, error) {
+	var progress io.Writer = os.Stderr
+	if !verbose {
+		progress = &bytes.Buffer{}
+	}
+
+	path := MirrorPath(cacheDir, url)
+	storage := filesystem.NewStorage(osfs.New(path), cache.NewObjectLRUDefault())
+	r, err := git.Open(storage, nil)
+	if err == git.ErrRepositoryNotExists {
+		if verbose {
+			log.Printf("INFO: creating mirror of %s in %s", url, path)
+		}
+		if r, err = git.Init(storage, nil); err == nil {
+			_, err = r.CreateRemote(&config.RemoteConfig{
+				Name:  git.DefaultRemoteName,
+				URLs:  []string{url},
+				Fetch: []config.RefSpec{"+refs/heads/*:refs/heads/*"},
+			})
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open the mirror of %s in %s, %s", url, path, err)
+	}
+
+	if err = fetchMirror(ctx, r, url, progress); err != nil {
+		return nil, err
+	}
+	return &Repository{Url: url, Verbose: verbose, repository: r}, nil
+}
+
+// fetchMirror fetches the tips of the branches and the tags of the remote into the mirror, and removes the
+// branches and tags which no longer exist on the remote.
+func fetchMirror(ctx context.Context, r *git.Repository, url string, progress io.Writer) error {
+	auth, _, err := GetAuth(ctx, url)
+	if err != nil {
+		return err
+	}
+	remote, err := r.Remote(git.DefaultRemoteName)
+	if err != nil {
+		return err
+	}
+	err = remote.FetchContext(ctx, &git.FetchOptions{
+		RemoteName: git.DefaultRemoteName,
+		RefSpecs:   []config.RefSpec{"+refs/heads/*:refs/heads/*", "+refs/tags/*:refs/tags/*"},
+		Depth:      1,
+		Auth:       auth,
+		Progress:   progress,
+	})
+	if err != nil && err != git.NoErrAlreadyUpToDate {
+		return fmt.Errorf("failed to fetch all branches from %s, %s", url, err)
+	}
+
+	refs, err := remote.List(&git.ListOptions{Auth: auth})
+	if err != nil {
+		return fmt.Errorf("failed to list the branches of %s, %s", url, err)
+	}
+	exists := make(map[plumbing.ReferenceName]bool, len(refs))
+	for _, ref := range refs {
+		exists[ref.Name()] = true
+	}
+	local, err := r.References()
+	if err != nil {
+		return err
+	}
+	stale := make([]plumbing.ReferenceName, 0)
+	_ = local.ForEach(func(ref *plumbing.Reference) error {
+		if (ref.Name().IsBranch() || ref.Name().IsTag()) && !exists[ref.Name()] {
+			stale = append(stale, ref.Name())
+		}
+		return nil
+	})
+	for _, name := range stale {
+		if err = r.Storer.RemoveReference(name); err != nil {
+			return fmt.Errorf("failed to remove %s from the mirror of %s, %s", name.Short(), url, err)
+		}
+	}
+	return nil
+}
+
+// OpenReadOnly opens the repository at url to read, from its mirror in the cache directory if specified.
+func OpenReadOnly(ctx context.Context, url string, cacheDir string, verbose bool) (*Repository, error) {
+	if cacheDir != "" {
+		return OpenMirror(ctx, url, cacheDir, verbose)
+	}
+	return Open(ctx, url, true, verbose)
+}
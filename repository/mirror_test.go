@@ -0,0 +1,101 @@
+package repository
+
+import (
+	"context"
+	"gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func commitFile(t *testing.T, g *git.Repository, dir string, name string, content string) plumbing.Hash {
+	if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	worktree, err := g.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = worktree.Add(name); err != nil {
+		t.Fatal(err)
+	}
+	hash, err := worktree.Commit("update "+name, &git.CommitOptions{
+		Author: &object.Signature{Name: "test", Email: "test@example.com", When: time.Now()},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return hash
+}
+
+func branchHashes(t *testing.T, r *Repository) map[string]string {
+	result := make(map[string]string)
+	iter, err := r.Branches()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = iter.ForEach(func(ref *plumbing.Reference) error {
+		result[ref.Name().Short()] = ref.Hash().String()
+		return nil
+	})
+	return result
+}
+
+func TestOpenMirror(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fromage-mirror")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	source, cacheDir := filepath.Join(dir, "source"), filepath.Join(dir, "cache")
+
+	g, err := git.PlainInit(source, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := commitFile(t, g, source, "Dockerfile", "FROM golang:1.12\n")
+	feature := plumbing.NewHashReference(plumbing.NewBranchReferenceName("feature"), first)
+	if err = g.Storer.SetReference(feature); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := OpenMirror(context.Background(), source, cacheDir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := map[string]string{"master": first.String(), "feature": first.String()}
+	if branches := branchHashes(t, r); !reflect.DeepEqual(branches, expect) {
+		t.Fatalf("expected branches %v in the mirror, got %v", expect, branches)
+	}
+	if _, err = os.Stat(filepath.Join(MirrorPath(cacheDir, source), "config")); err != nil {
+		t.Fatalf("expected the mirror in %s, %s", MirrorPath(cacheDir, source), err)
+	}
+
+	second := commitFile(t, g, source, "Dockerfile", "FROM golang:1.13\n")
+	if err = g.Storer.RemoveReference(feature.Name()); err != nil {
+		t.Fatal(err)
+	}
+	r, err = OpenMirror(context.Background(), source, cacheDir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect = map[string]string{"master": second.String()}
+	if branches := branchHashes(t, r); !reflect.DeepEqual(branches, expect) {
+		t.Fatalf("expected branches %v after the incremental fetch, got %v", expect, branches)
+	}
+
+	err = r.ForEachDockerfile(context.Background(), nil, func(branch *plumbing.Reference, dockerfile string, content []byte) error {
+		if string(content) != "FROM golang:1.13\n" {
+			t.Fatalf("expected the Dockerfile of the latest commit, got %s", string(content))
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
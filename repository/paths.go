@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// PathFilter selects the Dockerfiles by their path in the repository, with patterns like services/payments/**.
+// In a pattern, ** matches any number of directories and the other elements are matched as by path.Match. A
+// pattern which matches a directory selects all Dockerfiles below it. An empty filter selects all Dockerfiles.
+type PathFilter []string
+
+// NewPathFilter returns the filter for the patterns, or an error if a pattern is malformed.
+func NewPathFilter(patterns []string) (PathFilter, error) {
+	result := make(PathFilter, 0, len(patterns))
+	for _, pattern := range patterns {
+		p := path.Clean(strings.TrimPrefix(pattern, "/"))
+		if p == "." || p == ".." || strings.HasPrefix(p, "../") {
+			return nil, fmt.Errorf("%s is not a path in the repository", pattern)
+		}
+		for _, element := range strings.Split(p, "/") {
+			if _, err := path.Match(element, ""); err != nil {
+				return nil, fmt.Errorf("invalid path pattern %s, %s", pattern, err)
+			}
+		}
+		result = append(result, p)
+	}
+	return result, nil
+}
+
+// Match returns true if the filter selects the file.
+func (f PathFilter) Match(filename string) bool {
+	return f.match(filename, false)
+}
+
+// MayContain returns true if the filter may select files below the directory. Directories for which it
+// returns false need not be read.
+func (f PathFilter) MayContain(directory string) bool {
+	return f.match(directory, true)
+}
+
+func (f PathFilter) match(name string, directory bool) bool {
+	if len(f) == 0 {
+		return true
+	}
+	elements := strings.Split(path.Clean(name), "/")
+	for _, pattern := range f {
+		if matchElements(strings.Split(pattern, "/"), elements, directory) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchElements returns true if the path elements match the pattern, or are below a directory which matches
+// the pattern. For a directory, it is sufficient that the elements match the start of the pattern.
+func matchElements(pattern []string, elements []string, directory bool) bool {
+	if len(pattern) == 0 {
+		return true
+	}
+	if len(elements) == 0 {
+		if directory {
+			return true
+		}
+		for _, p := range pattern {
+			if p != "**" {
+				return false
+			}
+		}
+		return true
+	}
+	if pattern[0] == "**" {
+		return matchElements(pattern[1:], elements, directory) || matchElements(pattern, elements[1:], directory)
+	}
+	if ok, _ := path.Match(pattern[0], elements[0]); !ok {
+		return false
+	}
+	return matchElements(pattern[1:], elements[1:], directory)
+}
+
+// String returns the patterns of the filter, separated by commas.
+func (f PathFilter) String() string {
+	return strings.Join(f, ",")
+}
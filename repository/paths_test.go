@@ -0,0 +1,103 @@
+package repository
+
+import (
+	"context"
+	"gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestPathFilter(t *testing.T) {
+	tests := []struct {
+		patterns  []string
+		name      string
+		directory bool
+		expect    bool
+	}{
+		{nil, "services/payments/Dockerfile", false, true},
+		{[]string{"services/payments/**"}, "services/payments/Dockerfile", false, true},
+		{[]string{"services/payments/**"}, "services/payments/api/Dockerfile", false, true},
+		{[]string{"services/payments/**"}, "services/orders/Dockerfile", false, false},
+		{[]string{"services/payments/**"}, "Dockerfile", false, false},
+		{[]string{"services/payments"}, "services/payments/api/Dockerfile", false, true},
+		{[]string{"/services/*/Dockerfile"}, "services/orders/Dockerfile", false, true},
+		{[]string{"services/*/Dockerfile"}, "services/orders/api/Dockerfile", false, false},
+		{[]string{"**/api/Dockerfile"}, "services/orders/api/Dockerfile", false, true},
+		{[]string{"**/api/Dockerfile"}, "api/Dockerfile", false, true},
+		{[]string{"services/payments/**"}, "services", true, true},
+		{[]string{"services/payments/**"}, "services/payments/api", true, true},
+		{[]string{"services/payments/**"}, "services/orders", true, false},
+		{[]string{"services/payments/**"}, "libs", true, false},
+		{[]string{"**/api/Dockerfile"}, "libs", true, true},
+		{[]string{"libs/*", "services/payments/**"}, "libs/common", true, true},
+	}
+	for _, test := range tests {
+		filter, err := NewPathFilter(test.patterns)
+		if err != nil {
+			t.Fatal(err)
+		}
+		result := filter.Match(test.name)
+		if test.directory {
+			result = filter.MayContain(test.name)
+		}
+		if result != test.expect {
+			t.Fatalf("expected %v for %s with %v, got %v", test.expect, test.name, test.patterns, result)
+		}
+	}
+
+	for _, pattern := range []string{"services/[payments", "../services", "/"} {
+		if _, err := NewPathFilter([]string{pattern}); err == nil {
+			t.Fatalf("expected an error for pattern %s", pattern)
+		}
+	}
+}
+
+func TestForEachDockerfileInPaths(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fromage-paths")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	g, err := git.PlainInit(dir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := []string{"Dockerfile", "services/orders/Dockerfile", "services/payments/Dockerfile", "services/payments/api/Dockerfile"}
+	for _, name := range names {
+		if err = os.MkdirAll(filepath.Join(dir, filepath.Dir(name)), 0755); err != nil {
+			t.Fatal(err)
+		}
+		commitFile(t, g, dir, name, "FROM golang:1.12\n")
+	}
+
+	paths, err := NewPathFilter([]string{"services/payments/**"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := []string{"services/payments/Dockerfile", "services/payments/api/Dockerfile"}
+	for _, open := range []func() (*Repository, error){
+		func() (*Repository, error) { return Open(context.Background(), dir, true, false) },
+		func() (*Repository, error) { return OpenWorktree(dir, false) },
+	} {
+		r, err := open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		r.Paths = paths
+		found := make([]string, 0)
+		err = r.ForEachDockerfile(context.Background(), nil, func(branch *plumbing.Reference, dockerfile string, content []byte) error {
+			found = append(found, dockerfile)
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(found, expect) {
+			t.Fatalf("expected %v in %s, got %v", expect, r.Url, found)
+		}
+	}
+}
@@ -0,0 +1,210 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"gopkg.in/src-d/go-git.v4/config"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// commitSearchDepth is the number of commits fetched on every branch and tag to find a commit by its hash.
+const commitSearchDepth = 1000
+
+var hashRegExp = regexp.MustCompile(`^[0-9a-f]{7,40}$`)
+
+// RefType returns the type of the reference: branch, tag or commit.
+func RefType(ref *plumbing.Reference) string {
+	switch {
+	case ref.Name().IsBranch():
+		return "branch"
+	case ref.Name().IsTag():
+		return "tag"
+	default:
+		return "commit"
+	}
+}
+
+// SelectBranches returns the branches, or all branches if none are specified. For a worktree, it returns the
+// checked out commit.
+func (r *Repository) SelectBranches(branches []string) ([]*plumbing.Reference, error) {
+	if r.inPlace {
+		return []*plumbing.Reference{r.head}, nil
+	}
+	iter, err := r.Branches()
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*plumbing.Reference, 0)
+	_ = iter.ForEach(func(ref *plumbing.Reference) error {
+		if DesiredBranch(ref, branches) {
+			result = append(result, ref)
+		}
+		return nil
+	})
+	return result, nil
+}
+
+// ResolveRefs returns the branches and tags matching any of the patterns, and the commits of the patterns
+// which are a commit hash. A pattern matches the full or short name of a reference, like refs/tags/v* or
+// main. Tags refer to the commit they point to. It is an error if a pattern matches nothing.
+func (r *Repository) ResolveRefs(ctx context.Context, patterns []string) ([]*plumbing.Reference, error) {
+	if r.inPlace {
+		return nil, fmt.Errorf("refs cannot be selected in the worktree %s", r.Url)
+	}
+	refs, err := r.branchesAndTags()
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*plumbing.Reference, 0)
+	found := make(map[plumbing.ReferenceName]bool)
+	for _, pattern := range patterns {
+		matched := false
+		for _, ref := range refs {
+			if !matchesRef(pattern, ref.Name()) {
+				continue
+			}
+			matched = true
+			if found[ref.Name()] {
+				continue
+			}
+			found[ref.Name()] = true
+			commit, err := r.peel(ref)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, plumbing.NewHashReference(ref.Name(), commit))
+		}
+		if matched {
+			continue
+		}
+		if !hashRegExp.MatchString(pattern) {
+			return nil, fmt.Errorf("no branch or tag matches %s", pattern)
+		}
+
+		commit, err := r.findCommit(ctx, pattern)
+		if err != nil {
+			return nil, err
+		}
+		name := plumbing.ReferenceName(commit.String())
+		if !found[name] {
+			found[name] = true
+			result = append(result, plumbing.NewHashReference(name, commit))
+		}
+	}
+	return result, nil
+}
+
+func matchesRef(pattern string, name plumbing.ReferenceName) bool {
+	for _, n := range []string{name.String(), name.Short()} {
+		if ok, _ := path.Match(pattern, n); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// RefNames returns the full names of all branches and tags of the repository.
+func (r *Repository) RefNames() (map[string]bool, error) {
+	refs, err := r.branchesAndTags()
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string]bool, len(refs))
+	for _, ref := range refs {
+		result[ref.Name().String()] = true
+	}
+	return result, nil
+}
+
+// branchesAndTags returns all branches and tags of the repository, sorted by name.
+func (r *Repository) branchesAndTags() ([]*plumbing.Reference, error) {
+	iter, err := r.repository.References()
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve the references of repository %s, %s", r.Url, err)
+	}
+	result := make([]*plumbing.Reference, 0)
+	_ = iter.ForEach(func(ref *plumbing.Reference) error {
+		if ref.Type() == plumbing.HashReference && (ref.Name().IsBranch() || ref.Name().IsTag()) {
+			result = append(result, ref)
+		}
+		return nil
+	})
+	sort.Slice(result, func(i, j int) bool { return result[i].Name() < result[j].Name() })
+	return result, nil
+}
+
+// peel returns the commit the reference points to, following annotated tags.
+func (r *Repository) peel(ref *plumbing.Reference) (plumbing.Hash, error) {
+	tag, err := r.repository.TagObject(ref.Hash())
+	if err == plumbing.ErrObjectNotFound {
+		return ref.Hash(), nil
+	}
+	if err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("failed to read tag %s, %s", ref.Name().Short(), err)
+	}
+	commit, err := tag.Commit()
+	if err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("tag %s does not point to a commit, %s", ref.Name().Short(), err)
+	}
+	return commit.Hash, nil
+}
+
+// findCommit returns the commit with the hash or abbreviated hash. If it is not in a shallow clone, the
+// history of the branches and tags is fetched to find it.
+func (r *Repository) findCommit(ctx context.Context, hash string) (plumbing.Hash, error) {
+	result, err := r.lookupCommit(hash)
+	if err != nil || !result.IsZero() {
+		return result, err
+	}
+	if !r.isShallow() {
+		return plumbing.ZeroHash, fmt.Errorf("no branch, tag or commit matches %s", hash)
+	}
+
+	refSpecs := []config.RefSpec{"+refs/heads/*:refs/heads/*", "+refs/tags/*:refs/tags/*"}
+	if err = r.deepen(ctx, refSpecs, commitSearchDepth); err != nil {
+		return plumbing.ZeroHash, err
+	}
+	if result, err = r.lookupCommit(hash); err == nil && result.IsZero() {
+		err = fmt.Errorf("no branch, tag or commit in the last %d commits matches %s", commitSearchDepth, hash)
+	}
+	return result, err
+}
+
+// lookupCommit returns the commit with the hash or abbreviated hash, or the zero hash if it does not exist.
+func (r *Repository) lookupCommit(hash string) (plumbing.Hash, error) {
+	if len(hash) == 40 {
+		commit, err := r.repository.CommitObject(plumbing.NewHash(hash))
+		if err == plumbing.ErrObjectNotFound {
+			return plumbing.ZeroHash, nil
+		}
+		if err != nil {
+			return plumbing.ZeroHash, err
+		}
+		return commit.Hash, nil
+	}
+
+	iter, err := r.repository.CommitObjects()
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+	matches := make([]plumbing.Hash, 0)
+	_ = iter.ForEach(func(commit *object.Commit) error {
+		if strings.HasPrefix(commit.Hash.String(), hash) {
+			matches = append(matches, commit.Hash)
+		}
+		return nil
+	})
+	if len(matches) > 1 {
+		return plumbing.ZeroHash, fmt.Errorf("%s matches %d commits", hash, len(matches))
+	}
+	if len(matches) == 0 {
+		return plumbing.ZeroHash, nil
+	}
+	return matches[0], nil
+}
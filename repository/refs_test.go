@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"context"
+	"gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestResolveRefs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fromage-refs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	g, err := git.PlainInit(dir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := commitFile(t, g, dir, "Dockerfile", "FROM golang:1.12\n")
+	second := commitFile(t, g, dir, "Dockerfile", "FROM golang:1.13\n")
+	if _, err = g.CreateTag("v1.0.0", first, nil); err != nil {
+		t.Fatal(err)
+	}
+	_, err = g.CreateTag("v1.1.0", second, &git.CreateTagOptions{
+		Message: "release 1.1.0",
+		Tagger:  &object.Signature{Name: "test", Email: "test@example.com", When: time.Now()},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := Open(context.Background(), dir, true, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		patterns []string
+		expect   map[string]plumbing.Hash
+		types    map[string]string
+	}{
+		{[]string{"refs/tags/v*"},
+			map[string]plumbing.Hash{"refs/tags/v1.0.0": first, "refs/tags/v1.1.0": second},
+			map[string]string{"refs/tags/v1.0.0": "tag", "refs/tags/v1.1.0": "tag"}},
+		{[]string{"master", "v1.1.0"},
+			map[string]plumbing.Hash{"refs/heads/master": second, "refs/tags/v1.1.0": second},
+			map[string]string{"refs/heads/master": "branch", "refs/tags/v1.1.0": "tag"}},
+		{[]string{first.String()[0:7]},
+			map[string]plumbing.Hash{first.String(): first},
+			map[string]string{first.String(): "commit"}},
+	}
+	for _, test := range tests {
+		refs, err := r.ResolveRefs(context.Background(), test.patterns)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(refs) != len(test.expect) {
+			t.Fatalf("expected %d refs for %v, got %v", len(test.expect), test.patterns, refs)
+		}
+		for _, ref := range refs {
+			if test.expect[ref.Name().String()] != ref.Hash() {
+				t.Fatalf("expected %s at %s for %v, got %s", ref.Name(), test.expect[ref.Name().String()], test.patterns, ref.Hash())
+			}
+			if RefType(ref) != test.types[ref.Name().String()] {
+				t.Fatalf("expected %s to be a %s, got %s", ref.Name(), test.types[ref.Name().String()], RefType(ref))
+			}
+		}
+	}
+
+	if _, err = r.ResolveRefs(context.Background(), []string{"refs/tags/v2*"}); err == nil {
+		t.Fatalf("expected an error when no ref matches")
+	}
+	if err = r.CheckBranches([]string{"v1.0.0"}); err == nil {
+		t.Fatalf("expected an error when a tag is specified as branch")
+	}
+}
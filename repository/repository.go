@@ -0,0 +1,407 @@
+// Package repository clones git repositories and iterates over the Dockerfiles in their branches.
+package repository
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"gopkg.in/src-d/go-billy.v4"
+	"gopkg.in/src-d/go-billy.v4/osfs"
+	"gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/config"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/filemode"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+	"gopkg.in/src-d/go-git.v4/plumbing/storer"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path"
+	"sort"
+	"strings"
+)
+
+// Repository is a cloned or opened git repository in which Dockerfiles are read and updated.
+type Repository struct {
+	Url     string
+	Verbose bool
+
+	// Paths selects the Dockerfiles which are read and updated, defaults to all Dockerfiles.
+	Paths PathFilter
+
+	repository *git.Repository
+	filesystem billy.Filesystem
+
+	// inPlace indicates that the Dockerfiles are read and updated in the working tree
+	// at Url, without checking out branches or committing changes.
+	inPlace bool
+	head    *plumbing.Reference
+
+	// changes are the Dockerfiles written on every branch, to be committed.
+	changes map[plumbing.ReferenceName]*branchChanges
+}
+
+// Open clones the repository at url in memory. A local repository is opened in place, unless readOnly is
+// specified. Changes are committed on its branches, and only update the checked out files of the checked out branch.
+func Open(ctx context.Context, url string, readOnly bool, verbose bool) (*Repository, error) {
+	var progress io.Writer = os.Stderr
+	if !verbose {
+		progress = &bytes.Buffer{}
+	}
+
+	r, err := Clone(ctx, url, progress, readOnly)
+	if err != nil {
+		return nil, fmt.Errorf("failed to clone repository %s, %s", url, err)
+	}
+
+	return &Repository{Url: url, Verbose: verbose, repository: r}, nil
+}
+
+// OpenWorktree opens the directory at path to read and update the Dockerfiles in place. Branches are not
+// checked out, and changes are neither staged nor committed. The directory does not need to be a git repository.
+func OpenWorktree(path string, verbose bool) (*Repository, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", path)
+	}
+
+	result := &Repository{Url: path, Verbose: verbose, filesystem: osfs.New(path), inPlace: true}
+	result.head = plumbing.NewHashReference("", plumbing.ZeroHash)
+
+	if r, err := git.PlainOpenWithOptions(path, &git.PlainOpenOptions{DetectDotGit: true}); err == nil {
+		result.repository = r
+		if head, err := r.Head(); err == nil {
+			result.head = head
+		}
+	} else if err != git.ErrRepositoryNotExists {
+		return nil, fmt.Errorf("failed to open repository at %s, %s", path, err)
+	}
+	return result, nil
+}
+
+// IsWorktree returns true if the Dockerfiles are read and updated in place.
+func (r *Repository) IsWorktree() bool {
+	return r.inPlace
+}
+
+// Git returns the underlying git repository.
+func (r *Repository) Git() *git.Repository {
+	return r.repository
+}
+
+// IsLocal returns true if the repository was opened from a local path.
+func (r *Repository) IsLocal() bool {
+	return IsLocalEndpoint(r.Url)
+}
+
+func (r *Repository) Branches() (storer.ReferenceIter, error) {
+	branches, err := r.repository.Branches()
+	if err != nil {
+		return nil, fmt.Errorf("failed retrieve branches of repository %s, %s", r.Url, err)
+	}
+	return branches, nil
+}
+
+// CheckBranches returns an error if any of the branches does not exist.
+func (r *Repository) CheckBranches(branches []string) error {
+	if r.inPlace && len(branches) > 0 {
+		return fmt.Errorf("branches cannot be selected in the worktree %s", r.Url)
+	}
+	for _, branch := range branches {
+		found := false
+		iter, err := r.Branches()
+		if err != nil {
+			return err
+		}
+		_ = iter.ForEach(func(reference *plumbing.Reference) error {
+			found = found || (branch == reference.Name().Short() || branch == reference.Name().String())
+			return nil
+		})
+		if !found {
+			if _, err := r.repository.Reference(plumbing.NewTagReferenceName(branch), false); err == nil {
+				return fmt.Errorf("%s is a tag, not a branch", branch)
+			}
+			return fmt.Errorf("branch %s does not exist", branch)
+		}
+	}
+	return nil
+}
+
+func DesiredBranch(reference *plumbing.Reference, branches []string) bool {
+	if !reference.Name().IsBranch() {
+		return false
+	}
+	for _, branch := range branches {
+		if branch == reference.Name().Short() || branch == reference.Name().String() {
+			return true
+		}
+	}
+	return len(branches) == 0
+}
+
+// ForEachDockerfile calls m with the content of every Dockerfile on each of the branches. If no branches
+// are specified, all branches are visited.
+func (r *Repository) ForEachDockerfile(ctx context.Context, branches []string, m func(branch *plumbing.Reference, dockerfile string, content []byte) error) error {
+	refs, err := r.SelectBranches(branches)
+	if err != nil {
+		return err
+	}
+	return r.ForEachDockerfileAt(ctx, refs, m)
+}
+
+// ForEachDockerfileAt calls m with the content of every Dockerfile at each of the references. The Dockerfiles
+// are read from the tree of the commit of the reference, so nothing is checked out. In a worktree, the
+// Dockerfiles are read from the directory.
+func (r *Repository) ForEachDockerfileAt(ctx context.Context, refs []*plumbing.Reference, m func(ref *plumbing.Reference, dockerfile string, content []byte) error) error {
+	if r.inPlace {
+		return r.forEachDockerfileInWorktree(m)
+	}
+	for _, ref := range refs {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if r.Verbose {
+			log.Printf("reading %s\n", ref.Name().Short())
+		}
+		if err := r.forEachDockerfileInTree(ref, m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *Repository) forEachDockerfileInTree(ref *plumbing.Reference, m func(branch *plumbing.Reference, dockerfile string, content []byte) error) error {
+	commit, err := r.repository.CommitObject(ref.Hash())
+	if err != nil {
+		return fmt.Errorf("failed to read the commit of %s, %s", ref.Name().Short(), err)
+	}
+	tree, err := commit.Tree()
+	if err != nil {
+		return fmt.Errorf("failed to read the tree of %s, %s", ref.Name().Short(), err)
+	}
+	return r.walkTree(ref, tree, "", m)
+}
+
+// walkTree calls m with every Dockerfile in the tree selected by Paths. Directories in which Paths cannot
+// select a Dockerfile are skipped without reading their trees.
+func (r *Repository) walkTree(ref *plumbing.Reference, tree *object.Tree, prefix string, m func(branch *plumbing.Reference, dockerfile string, content []byte) error) error {
+	for _, entry := range tree.Entries {
+		name := prefix + entry.Name
+		if entry.Mode == filemode.Dir {
+			if !r.Paths.MayContain(name) {
+				continue
+			}
+			subtree, err := r.repository.TreeObject(entry.Hash)
+			if err != nil {
+				return fmt.Errorf("failed to read %s on %s, %s", name, ref.Name().Short(), err)
+			}
+			if err = r.walkTree(ref, subtree, name+"/", m); err != nil {
+				return err
+			}
+			continue
+		}
+		if entry.Name != "Dockerfile" || !entry.Mode.IsFile() || !r.Paths.Match(name) {
+			continue
+		}
+		content, err := r.readBlob(entry.Hash)
+		if err != nil {
+			return fmt.Errorf("failed to read %s on %s, %s", name, ref.Name().Short(), err)
+		}
+		if err = m(ref, name, content); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *Repository) readBlob(hash plumbing.Hash) ([]byte, error) {
+	blob, err := r.repository.BlobObject(hash)
+	if err != nil {
+		return nil, err
+	}
+	reader, err := blob.Reader()
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return ioutil.ReadAll(reader)
+}
+
+func (r *Repository) forEachDockerfileInWorktree(m func(branch *plumbing.Reference, dockerfile string, content []byte) error) error {
+	dockerfiles, err := findDockerfiles(r.filesystem, "/", r.Paths)
+	if err != nil {
+		return err
+	}
+	for _, dockerfile := range dockerfiles {
+		content, err := r.readFile(dockerfile)
+		if err != nil {
+			return err
+		}
+		if err = m(r.head, dockerfile, content); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Dockerfile is the content of a Dockerfile at a reference.
+type Dockerfile struct {
+	Ref     *plumbing.Reference
+	Path    string
+	Content []byte
+}
+
+// Dockerfiles returns all Dockerfiles at the references, as read by ForEachDockerfileAt.
+func (r *Repository) Dockerfiles(ctx context.Context, refs []*plumbing.Reference) ([]*Dockerfile, error) {
+	result := make([]*Dockerfile, 0)
+	err := r.ForEachDockerfileAt(ctx, refs, func(ref *plumbing.Reference, dockerfile string, content []byte) error {
+		result = append(result, &Dockerfile{Ref: ref, Path: dockerfile, Content: content})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// FindDockerfiles returns the paths of all Dockerfiles in the filesystem below filename.
+func FindDockerfiles(fs billy.Filesystem, filename string) ([]string, error) {
+	return findDockerfiles(fs, filename, nil)
+}
+
+// findDockerfiles returns the paths of the Dockerfiles below filename selected by paths. Directories in which
+// paths cannot select a Dockerfile are not read.
+func findDockerfiles(fs billy.Filesystem, filename string, paths PathFilter) ([]string, error) {
+	result := make([]string, 0)
+	file, err := fs.Stat(filename)
+	if err != nil {
+		return nil, err
+	}
+	if file.IsDir() {
+		if file.Name() == git.GitDirName || (filename != "/" && !paths.MayContain(filename)) {
+			return result, nil
+		}
+		dir, err := fs.ReadDir(filename)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, file = range dir {
+			fullPath := path.Join(filename, file.Name())
+			if filename == "/" {
+				fullPath = file.Name()
+			}
+			found, err := findDockerfiles(fs, fullPath, paths)
+			if err == nil {
+				result = append(result, found...)
+			} else {
+				return nil, err
+			}
+		}
+	} else {
+		if path.Base(file.Name()) == "Dockerfile" && paths.Match(filename) {
+			result = append(result, filename)
+		}
+	}
+	return result, nil
+}
+
+func (r *Repository) readFile(filename string) ([]byte, error) {
+	file, err := r.filesystem.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	content, err := ioutil.ReadAll(file)
+	if err != nil {
+		return nil, err
+	}
+	return content, nil
+}
+
+// WriteFile updates the Dockerfile on the branch. In a worktree, the file is written in place. Otherwise, the
+// change is recorded, to be committed on the branch by CommitAndPush.
+func (r *Repository) WriteFile(branch *plumbing.Reference, filename string, content []byte) error {
+	if !r.inPlace {
+		if r.changes == nil {
+			r.changes = make(map[plumbing.ReferenceName]*branchChanges)
+		}
+		changes, ok := r.changes[branch.Name()]
+		if !ok {
+			changes = &branchChanges{base: branch.Hash(), files: make(map[string][]byte)}
+			r.changes[branch.Name()] = changes
+		}
+		changes.files[filename] = content
+		return nil
+	}
+
+	file, err := r.filesystem.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	_, err = file.Write(content)
+	return err
+}
+
+// CommitAndPush commits the changes written on every branch with the message, and pushes the branches if
+// the repository is remote. The commits are created from the tree of the branch, so no files are checked out.
+// On a dry run, the branches which would be committed are only reported, and the changes are discarded.
+func (r *Repository) CommitAndPush(ctx context.Context, msg string, dryRun bool) error {
+	log.Printf("INFO: %s", strings.SplitN(msg, "\n", 2)[0])
+	if r.inPlace {
+		if !dryRun {
+			log.Printf("INFO: changes written to %s, not committed", r.Url)
+		} else {
+			log.Printf("INFO: changes would be written to %s", r.Url)
+		}
+		return nil
+	}
+
+	names := make([]string, 0, len(r.changes))
+	for name := range r.changes {
+		names = append(names, name.String())
+	}
+	sort.Strings(names)
+
+	refSpecs := make([]config.RefSpec, 0, len(names))
+	for _, name := range names {
+		branch := plumbing.ReferenceName(name)
+		if !dryRun {
+			hash, err := r.commitChanges(branch, r.changes[branch], msg)
+			if err != nil {
+				return err
+			}
+			log.Printf("INFO: changes committed with %s on %s", hash.String()[0:7], branch.Short())
+		} else {
+			log.Printf("INFO: changes would be committed on %s", branch.Short())
+		}
+		refSpecs = append(refSpecs, config.RefSpec(fmt.Sprintf("%s:%s", branch, branch)))
+	}
+	r.changes = nil
+
+	if r.IsLocal() || len(refSpecs) == 0 {
+		return nil
+	}
+
+	if !dryRun {
+		var progress io.Writer = os.Stderr
+		if !r.Verbose {
+			progress = &bytes.Buffer{}
+		}
+		log.Printf("INFO: pushing changes to %s", r.Url)
+
+		auth, _, err := GetAuth(ctx, r.Url)
+		if err != nil {
+			return err
+		}
+		return r.repository.PushContext(ctx, &git.PushOptions{RefSpecs: refSpecs, Auth: auth, Progress: progress})
+	} else {
+		log.Printf("INFO: changes would be pushed to %s", r.Url)
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"context"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestOpenWorktree(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fromage-worktree")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, name := range []string{"Dockerfile", "deploy/Dockerfile", "deploy/README.md"} {
+		filename := filepath.Join(dir, name)
+		if err = os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err = ioutil.WriteFile(filename, []byte("FROM golang:1.12\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	r, err := OpenWorktree(dir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !r.IsWorktree() || r.Git() != nil {
+		t.Fatalf("expected %s to be opened as a worktree without git repository", dir)
+	}
+	if err = r.CheckBranches([]string{"main"}); err == nil {
+		t.Fatalf("expected an error selecting a branch in a worktree")
+	}
+
+	found := make([]string, 0)
+	err = r.ForEachDockerfile(context.Background(), nil, func(branch *plumbing.Reference, dockerfile string, content []byte) error {
+		if branch.Name().Short() != "" {
+			t.Fatalf("expected no branch name, got %s", branch.Name().Short())
+		}
+		found = append(found, dockerfile)
+		return r.WriteFile(branch, dockerfile, []byte("FROM golang:1.13\n"))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(found, []string{"Dockerfile", "deploy/Dockerfile"}) {
+		t.Fatalf("expected Dockerfile and deploy/Dockerfile, got %v", found)
+	}
+
+	content, err := ioutil.ReadFile(filepath.Join(dir, "deploy/Dockerfile"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "FROM golang:1.13\n" {
+		t.Fatalf("expected the Dockerfile to be updated in place, got %s", string(content))
+	}
+
+	if err = r.CommitAndPush(context.Background(), "bumped", false); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"fmt"
+	neturl "net/url"
+	"regexp"
+	"strings"
+)
+
+var (
+	isSchemeRegExp   = regexp.MustCompile(`^[^:]+://`)
+	scpLikeUrlRegExp = regexp.MustCompile(`^(?:(?P<user>[^@]+)@)?(?P<host>[^:\s]+):(?:(?P<port>[0-9]{1,5})/)?(?P<path>[^\\].*)$`)
+)
+
+// MatchesScheme returns true if the given string matches a URL-like
+// format scheme.
+func MatchesScheme(url string) bool {
+	return isSchemeRegExp.MatchString(url)
+}
+
+// MatchesScpLike returns true if the given string matches an SCP-like
+// format scheme.
+func MatchesScpLike(url string) bool {
+	return scpLikeUrlRegExp.MatchString(url)
+}
+
+// FindScpLikeComponents returns the user, host, port and path of the
+// given SCP-like URL.
+func FindScpLikeComponents(url string) (user, host, port, path string) {
+	m := scpLikeUrlRegExp.FindStringSubmatch(url)
+	return m[1], m[2], m[3], m[4]
+}
+
+// IsLocalEndpoint returns true if the given URL string specifies a
+// local file endpoint.  For example, on a Linux machine,
+// `/home/user/src/go-git` would match as a local endpoint, but
+// `https://github.com/src-d/go-git` would not.
+func IsLocalEndpoint(url string) bool {
+	return !MatchesScheme(url) && !MatchesScpLike(url)
+}
+
+// WebUrl returns the web address of the repository on GitHub, GitLab or Bitbucket, or an empty
+// string if it is not hosted on any of these.
+func WebUrl(url string) string {
+	var host, path string
+	if MatchesScheme(url) {
+		u, err := neturl.Parse(url)
+		if err != nil {
+			return ""
+		}
+		host, path = u.Hostname(), u.Path
+	} else if MatchesScpLike(url) {
+		_, host, _, path = FindScpLikeComponents(url)
+	} else {
+		return ""
+	}
+
+	if !strings.Contains(host, "github") && !strings.Contains(host, "gitlab") && !strings.Contains(host, "bitbucket") {
+		return ""
+	}
+	path = strings.TrimSuffix(strings.Trim(path, "/"), ".git")
+	return fmt.Sprintf("https://%s/%s", host, path)
+}
+
+// FileUrl returns the web address of the line in the file at the commit, or an empty string if
+// the repository is not hosted on GitHub, GitLab or Bitbucket.
+func FileUrl(url, commit, path string, line int) string {
+	base := WebUrl(url)
+	if base == "" || commit == "" {
+		return ""
+	}
+
+	var result string
+	if strings.Contains(base, "gitlab") {
+		result = fmt.Sprintf("%s/-/blob/%s/%s", base, commit, path)
+	} else if strings.Contains(base, "bitbucket") {
+		result = fmt.Sprintf("%s/src/%s/%s", base, commit, path)
+	} else {
+		result = fmt.Sprintf("%s/blob/%s/%s", base, commit, path)
+	}
+
+	if line > 0 {
+		if strings.Contains(base, "bitbucket") {
+			result = fmt.Sprintf("%s#lines-%d", result, line)
+		} else {
+			result = fmt.Sprintf("%s#L%d", result, line)
+		}
+	}
+	return result
+}
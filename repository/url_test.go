@@ -0,0 +1,23 @@
+package repository
+
+import "testing"
+
+func TestFileUrl(t *testing.T) {
+	var tests = []struct {
+		url    string
+		expect string
+	}{
+		{"https://github.com/binxio/kritis", "https://github.com/binxio/kritis/blob/abc123/deploy/Dockerfile#L3"},
+		{"https://user@github.com/binxio/kritis.git", "https://github.com/binxio/kritis/blob/abc123/deploy/Dockerfile#L3"},
+		{"git@github.com:binxio/kritis.git", "https://github.com/binxio/kritis/blob/abc123/deploy/Dockerfile#L3"},
+		{"git@gitlab.com:binxio/kritis.git", "https://gitlab.com/binxio/kritis/-/blob/abc123/deploy/Dockerfile#L3"},
+		{"https://bitbucket.org/binxio/kritis.git", "https://bitbucket.org/binxio/kritis/src/abc123/deploy/Dockerfile#lines-3"},
+		{"https://git.example.com/binxio/kritis.git", ""},
+		{"/home/user/src/kritis", ""},
+	}
+	for _, test := range tests {
+		if result := FileUrl(test.url, "abc123", "deploy/Dockerfile", 3); result != test.expect {
+			t.Fatalf("expected %s for %s, got %s", test.expect, test.url, result)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package scan
+
+import (
+	"fmt"
+	"github.com/binxio/fromage/tag"
+	"github.com/google/go-containerregistry/pkg/name"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const day = 24 * time.Hour
+
+// now returns the current time, against which the age of the images is determined.
+var now = time.Now
+
+// ParseAge parses an age in days or weeks, like 90d or 12w, or a Go duration like 2160h.
+func ParseAge(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	for suffix, unit := range map[string]time.Duration{"d": day, "w": 7 * day} {
+		if strings.HasSuffix(s, suffix) {
+			n, err := strconv.Atoi(strings.TrimSuffix(s, suffix))
+			if err != nil || n < 0 {
+				return 0, fmt.Errorf("%s is not a valid age, expected a number of days like 90d", s)
+			}
+			return time.Duration(n) * unit, nil
+		}
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("%s is not a valid age, expected a number of days like 90d", s)
+	}
+	return d, nil
+}
+
+func days(d time.Duration) int {
+	return int(d / day)
+}
+
+// readCreated sets the creation timestamps of the image and of the newest version, and the age in days
+// derived from them. scratch is not an image, so it has no creation timestamp.
+func (r *DockerfileFromReference) readCreated() {
+	reference, err := name.ParseReference(r.Reference)
+	if err != nil || tag.IsScratch(reference) {
+		return
+	}
+	created, err := tag.GetCreated(reference)
+	if err != nil {
+		log.Printf("WARNING: %s", err)
+		return
+	}
+	r.Created = &created
+	r.Age = days(now().Sub(created))
+
+	if len(r.Newer) == 0 {
+		return
+	}
+	if newest, err := name.ParseReference(r.NewerReference(r.Newer[len(r.Newer)-1])); err == nil {
+		if newestCreated, err := tag.GetCreated(newest); err == nil {
+			r.NewestCreated = &newestCreated
+			r.NewestAge = days(now().Sub(newestCreated))
+			r.Behind = days(newestCreated.Sub(created))
+		} else {
+			log.Printf("WARNING: %s", err)
+		}
+	}
+}
+
+// HasAges returns true if the creation timestamp of any of the images is known.
+func (r DockerfileFromReferences) HasAges() bool {
+	for _, reference := range r {
+		if reference.Created != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// FilterByMaxAge returns the references with an image created longer than maxAge ago. References
+// of which the creation timestamp is unknown are not returned.
+func (r DockerfileFromReferences) FilterByMaxAge(maxAge time.Duration) DockerfileFromReferences {
+	result := make(DockerfileFromReferences, 0, len(r))
+	for _, reference := range r {
+		if reference.Created != nil && now().Sub(*reference.Created) > maxAge {
+			result = append(result, reference)
+		}
+	}
+	return result
+}
+
+func formatDays(d int, known bool) string {
+	if !known {
+		return "-"
+	}
+	return fmt.Sprintf("%dd", d)
+}
@@ -0,0 +1,124 @@
+package scan
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestParseAge(t *testing.T) {
+	var tests = []struct {
+		age    string
+		expect time.Duration
+		valid  bool
+	}{
+		{"90d", 90 * 24 * time.Hour, true},
+		{"2w", 14 * 24 * time.Hour, true},
+		{"36h", 36 * time.Hour, true},
+		{"d", 0, false},
+		{"-1d", 0, false},
+		{"ninety days", 0, false},
+	}
+	for _, test := range tests {
+		result, err := ParseAge(test.age)
+		if test.valid != (err == nil) {
+			t.Fatalf("expected %s to be valid %v, got %v", test.age, test.valid, err)
+		}
+		if result != test.expect {
+			t.Fatalf("expected %s for %s, got %s", test.expect, test.age, result)
+		}
+	}
+}
+
+func TestFilterByMaxAge(t *testing.T) {
+	defer func() { now = time.Now }()
+	now = func() time.Time { return time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC) }
+
+	old := time.Date(2023, time.June, 1, 0, 0, 0, 0, time.UTC)
+	recent := time.Date(2023, time.December, 1, 0, 0, 0, 0, time.UTC)
+	references := DockerfileFromReferences{
+		{Reference: "node:16", Created: &old},
+		{Reference: "node:20", Created: &recent},
+		{Reference: "node:21"},
+	}
+
+	result := references.FilterByMaxAge(90 * day)
+	if len(result) != 1 || result[0].Reference != "node:16" {
+		t.Fatalf("expected only node:16 to be older than 90 days, got %v", result.ExtractReferences())
+	}
+}
+
+func TestReadReferencesRetrievesCreatedOnlyWhenRequested(t *testing.T) {
+	var configs int32
+	handler := registry.New(registry.Logger(log.New(ioutil.Discard, "", 0)))
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet && strings.Contains(r.URL.Path, "/blobs/") {
+			atomic.AddInt32(&configs, 1)
+		}
+		handler.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+	u, _ := url.Parse(server.URL)
+
+	image, err := random.Image(64, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	created := time.Date(2022, time.May, 23, 10, 0, 0, 0, time.UTC)
+	if image, err = mutate.CreatedAt(image, v1.Time{Time: created}); err != nil {
+		t.Fatal(err)
+	}
+	reference := fmt.Sprintf("%s/library/node:16", u.Host)
+	ref, _ := name.ParseReference(reference)
+	if err = remote.Write(ref, image); err != nil {
+		t.Fatal(err)
+	}
+	content := []byte("FROM " + reference + " AS build\nFROM scratch\n")
+
+	var output bytes.Buffer
+	log.SetOutput(&output)
+	defer log.SetOutput(os.Stderr)
+
+	var tests = []struct {
+		options Options
+		created bool
+	}{
+		{Options{}, false},
+		{Options{Ages: true}, true},
+		{Options{MaxAge: 90 * day}, true},
+	}
+	for _, test := range tests {
+		atomic.StoreInt32(&configs, 0)
+		references := ReadReferences(content, "master", "Dockerfile", test.options)
+		if len(references) != 2 {
+			t.Fatalf("expected 2 references, got %v", references.ExtractReferences())
+		}
+		if (references[0].Created != nil) != test.created {
+			t.Fatalf("expected the creation timestamp to be retrieved %v with %+v, got %v", test.created, test.options, references[0].Created)
+		}
+		if !test.created && atomic.LoadInt32(&configs) != 0 {
+			t.Fatalf("expected no image configuration to be retrieved, got %d requests", configs)
+		}
+		if references[1].Created != nil {
+			t.Fatalf("expected no creation timestamp for scratch, got %s", references[1].Created)
+		}
+	}
+	if strings.Contains(output.String(), "scratch") {
+		t.Fatalf("expected the image configuration of scratch not to be retrieved, got\n%s", output.String())
+	}
+}
@@ -0,0 +1,33 @@
+package scan
+
+import (
+	"context"
+	"github.com/binxio/fromage/repository"
+)
+
+// CheckReferences returns the container image references of all Dockerfiles in the branches of the
+// repository which fail the check, as reported by fromage check.
+func CheckReferences(ctx context.Context, r *repository.Repository, options Options) (DockerfileFromReferences, error) {
+	references, err := ListReferences(ctx, r, options)
+	if err != nil {
+		return nil, err
+	}
+	return references.Check(options), nil
+}
+
+// Check returns the references which are out of date, limited to the update levels in FailOn and the
+// images older than MaxAge, if set. With FailOnEol, references past their end-of-life are always returned.
+func (r DockerfileFromReferences) Check(options Options) DockerfileFromReferences {
+	endOfLife := r.FilterEndOfLife()
+	result := r.FilterOutOfDate()
+	if len(options.FailOn) > 0 {
+		result = result.FilterByUpdateLevel(options.FailOn)
+	}
+	if options.MaxAge > 0 {
+		result = result.FilterByMaxAge(options.MaxAge)
+	}
+	if options.FailOnEol {
+		result = result.Union(endOfLife)
+	}
+	return result
+}
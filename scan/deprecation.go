@@ -0,0 +1,53 @@
+package scan
+
+import (
+	"fmt"
+	"github.com/binxio/fromage/deprecation"
+	"github.com/google/go-containerregistry/pkg/name"
+)
+
+// readDeprecation marks the reference as deprecated if any of the rules applies, with the recommended replacement.
+func (r *DockerfileFromReference) readDeprecation(rules deprecation.Rules) {
+	reference, err := name.ParseReference(r.Reference)
+	if err != nil {
+		return
+	}
+	if rule, replacement, ok := rules.Find(reference); ok {
+		r.Deprecated = true
+		r.DeprecationReason = rule.Reason
+		r.Replacement = replacement
+	}
+}
+
+// deprecated returns the replacement of a deprecated reference for the text output, yes if there is no
+// replacement, or "-" if the reference is not deprecated.
+func (r DockerfileFromReference) deprecated() string {
+	if !r.Deprecated {
+		return "-"
+	}
+	if r.Replacement != "" {
+		return r.Replacement
+	}
+	return "yes"
+}
+
+// HasDeprecated returns true if any of the references is deprecated.
+func (r DockerfileFromReferences) HasDeprecated() bool {
+	for _, reference := range r {
+		if reference.Deprecated {
+			return true
+		}
+	}
+	return false
+}
+
+func (r DockerfileFromReference) deprecationMessage() string {
+	message := "deprecated"
+	if r.DeprecationReason != "" {
+		message = fmt.Sprintf("%s, %s", message, r.DeprecationReason)
+	}
+	if r.Replacement != "" {
+		message = fmt.Sprintf("%s, use %s", message, r.Replacement)
+	}
+	return message
+}
@@ -0,0 +1,57 @@
+package scan
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/binxio/fromage/deprecation"
+	"testing"
+)
+
+func TestReadDeprecation(t *testing.T) {
+	rules := deprecation.Builtin()
+	references := DockerfileFromReferences{
+		{Reference: "openjdk:11-jre", Path: "Dockerfile", Line: 1, Column: 6},
+		{Reference: "centos:7", Path: "Dockerfile", Line: 2, Column: 6},
+		{Reference: "eclipse-temurin:17", Path: "Dockerfile", Line: 3, Column: 6},
+	}
+	for _, reference := range references {
+		reference.readDeprecation(rules)
+	}
+
+	var tests = []struct {
+		deprecated bool
+		output     string
+	}{
+		{true, "eclipse-temurin:11-jre"},
+		{true, "yes"},
+		{false, "-"},
+	}
+	for i, test := range tests {
+		if references[i].Deprecated != test.deprecated || references[i].deprecated() != test.output {
+			t.Fatalf("expected %s to be deprecated %v with %s, got %v", references[i].Reference,
+				test.deprecated, test.output, references[i].deprecated())
+		}
+	}
+	if result := references.FilterOutOfDate(); len(result) != 2 {
+		t.Fatalf("expected the deprecated references to be reported, got %v", result.ExtractReferences())
+	}
+
+	var out bytes.Buffer
+	if err := references.Output(&out, "sarif", false); err != nil {
+		t.Fatal(err)
+	}
+	var log sarifLog
+	if err := json.Unmarshal(out.Bytes(), &log); err != nil {
+		t.Fatal(err)
+	}
+	results := log.Runs[0].Results
+	if len(results) != 2 || results[0].RuleId != deprecatedRuleId {
+		t.Fatalf("expected 2 %s results, got %s", deprecatedRuleId, out.String())
+	}
+	if fix := results[0].Fixes[0].ArtifactChanges[0].Replacements[0]; fix.InsertedContent.Text != "eclipse-temurin:11-jre" {
+		t.Fatalf("expected fix eclipse-temurin:11-jre, got %s", fix.InsertedContent.Text)
+	}
+	if len(results[1].Fixes) != 0 {
+		t.Fatalf("expected no fix for a deprecated image without replacement")
+	}
+}
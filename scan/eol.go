@@ -0,0 +1,75 @@
+package scan
+
+import (
+	"github.com/binxio/fromage/eol"
+	"github.com/google/go-containerregistry/pkg/name"
+)
+
+// readEndOfLife sets the release cycle of the reference from the dataset, and whether it is past its end-of-life.
+func (r *DockerfileFromReference) readEndOfLife(dataset *eol.Dataset) {
+	reference, err := name.NewTag(r.Reference)
+	if err != nil {
+		return
+	}
+	if cycle, ok := dataset.Lookup(reference); ok {
+		r.Cycle = string(cycle.Cycle)
+		r.EndOfLife = cycle.IsEndOfLife(now())
+		if !cycle.EOL.Date.IsZero() {
+			r.EndOfLifeDate = cycle.String()
+		}
+	}
+}
+
+// endOfLife returns the end-of-life date, yes or no for the text output, or "-" if the release cycle is unknown.
+func (r DockerfileFromReference) endOfLife() string {
+	if r.Cycle == "" {
+		return "-"
+	}
+	if r.EndOfLife {
+		if r.EndOfLifeDate != "" {
+			return "yes, " + r.EndOfLifeDate
+		}
+		return "yes"
+	}
+	if r.EndOfLifeDate != "" {
+		return r.EndOfLifeDate
+	}
+	return "no"
+}
+
+// HasReleaseCycles returns true if the release cycle of any of the references is known.
+func (r DockerfileFromReferences) HasReleaseCycles() bool {
+	for _, reference := range r {
+		if reference.Cycle != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// FilterEndOfLife returns the references which are past their end-of-life.
+func (r DockerfileFromReferences) FilterEndOfLife() DockerfileFromReferences {
+	result := make(DockerfileFromReferences, 0, len(r))
+	for _, reference := range r {
+		if reference.EndOfLife {
+			result = append(result, reference)
+		}
+	}
+	return result
+}
+
+// Union returns the references, followed by the references of o which are not already included.
+func (r DockerfileFromReferences) Union(o DockerfileFromReferences) DockerfileFromReferences {
+	included := make(map[*DockerfileFromReference]bool, len(r))
+	result := make(DockerfileFromReferences, 0, len(r)+len(o))
+	for _, reference := range r {
+		included[reference] = true
+		result = append(result, reference)
+	}
+	for _, reference := range o {
+		if !included[reference] {
+			result = append(result, reference)
+		}
+	}
+	return result
+}
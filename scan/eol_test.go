@@ -0,0 +1,55 @@
+package scan
+
+import (
+	"github.com/binxio/fromage/eol"
+	"testing"
+	"time"
+)
+
+func TestReadEndOfLife(t *testing.T) {
+	defer func() { now = time.Now }()
+	now = func() time.Time { return time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC) }
+
+	dataset := eol.Snapshot()
+	var tests = []struct {
+		reference string
+		cycle     string
+		endOfLife bool
+		output    string
+	}{
+		{"python:3.7", "3.7", true, "yes, 2023-06-27"},
+		{"node:20-alpine", "20", false, "2026-04-30"},
+		{"golang:1.24", "1.24", false, "no"},
+		{"gcr.io/distroless/base:latest", "", false, "-"},
+	}
+	for _, test := range tests {
+		reference := DockerfileFromReference{Reference: test.reference}
+		reference.readEndOfLife(dataset)
+		if reference.Cycle != test.cycle || reference.EndOfLife != test.endOfLife {
+			t.Fatalf("expected cycle %q end-of-life %v for %s, got %q %v", test.cycle, test.endOfLife,
+				test.reference, reference.Cycle, reference.EndOfLife)
+		}
+		if reference.endOfLife() != test.output {
+			t.Fatalf("expected %s for %s, got %s", test.output, test.reference, reference.endOfLife())
+		}
+	}
+}
+
+func TestFilterEndOfLife(t *testing.T) {
+	references := DockerfileFromReferences{
+		{Reference: "python:3.7", Cycle: "3.7", EndOfLife: true},
+		{Reference: "golang:1.12", Newer: []string{"1.13"}},
+		{Reference: "node:14", Cycle: "14", EndOfLife: true, Newer: []string{"16"}},
+	}
+
+	result := references.FilterOutOfDate().Union(references.FilterEndOfLife())
+	expect := []string{"golang:1.12", "node:14", "python:3.7"}
+	if len(result) != len(expect) {
+		t.Fatalf("expected %v, got %v", expect, result.ExtractReferences())
+	}
+	for i, reference := range result {
+		if reference.Reference != expect[i] {
+			t.Fatalf("expected %v, got %s at %d", expect, reference.Reference, i)
+		}
+	}
+}
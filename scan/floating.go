@@ -0,0 +1,52 @@
+package scan
+
+import (
+	"fmt"
+	"github.com/binxio/fromage/tag"
+	"github.com/google/go-containerregistry/pkg/name"
+	"log"
+)
+
+// readFloating marks the reference as floating if it has no tag or a moving tag like latest, and resolves
+// the versioned tag which currently refers to the same image.
+func (r *DockerfileFromReference) readFloating() {
+	reference, err := name.ParseReference(r.Reference)
+	if err != nil || !tag.IsFloating(reference) {
+		return
+	}
+	r.Floating = true
+	if resolved, err := tag.ResolveFloating(reference.(name.Tag)); err == nil {
+		r.Resolved = resolved.String()
+	} else {
+		log.Printf("WARNING: %s", err)
+	}
+}
+
+// floating returns the resolved version of a floating reference for the text output, yes if it could not
+// be resolved, or "-" if the reference is not floating.
+func (r DockerfileFromReference) floating() string {
+	if !r.Floating {
+		return "-"
+	}
+	if r.Resolved != "" {
+		return r.Resolved
+	}
+	return "yes"
+}
+
+// HasFloating returns true if any of the references is floating.
+func (r DockerfileFromReferences) HasFloating() bool {
+	for _, reference := range r {
+		if reference.Floating {
+			return true
+		}
+	}
+	return false
+}
+
+func (r DockerfileFromReference) floatingMessage() string {
+	if r.Resolved != "" {
+		return fmt.Sprintf("floating tag, currently %s", r.Resolved)
+	}
+	return "floating tag"
+}
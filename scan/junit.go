@@ -0,0 +1,145 @@
+package scan
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	TestCases []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// failed returns true if the reference is out of date, end-of-life or violates the policy.
+func (r DockerfileFromReference) failed() bool {
+	return r.IsOutOfDate() || r.EndOfLife || len(r.Violations) > 0
+}
+
+func (r DockerfileFromReference) junitFailure() *junitFailure {
+	if !r.failed() {
+		return nil
+	}
+	messages := make([]string, 0, 4+len(r.Violations))
+	if len(r.Newer) > 0 {
+		messages = append(messages, fmt.Sprintf("newer versions available: %s", strings.Join(r.Newer, ", ")))
+	}
+	if len(r.MissingPlatforms) > 0 {
+		messages = append(messages, fmt.Sprintf("missing platforms: %s", strings.Join(r.MissingPlatforms, ", ")))
+	}
+	if r.Deprecated {
+		messages = append(messages, r.deprecationMessage())
+	}
+	if r.Floating {
+		messages = append(messages, r.floatingMessage())
+	}
+	if r.EndOfLife {
+		messages = append(messages, fmt.Sprintf("release cycle %s is end-of-life", r.Cycle))
+	}
+	messages = append(messages, r.violationMessages()...)
+	message := strings.Join(messages, "; ")
+	location := r.Path
+	if r.Line > 0 {
+		location = fmt.Sprintf("%s:%d", r.Path, r.Line)
+	}
+	if !r.IsOutOfDate() && !r.EndOfLife {
+		return &junitFailure{
+			Message: message,
+			Type:    "policy-violation",
+			Text:    fmt.Sprintf("%s in %s violates the policy, %s", r.Reference, location, message),
+		}
+	}
+	return &junitFailure{
+		Message: message,
+		Type:    "out-of-date",
+		Text:    fmt.Sprintf("%s in %s is out of date, %s", r.Reference, location, message),
+	}
+}
+
+func (s *junitTestSuite) add(testCase junitTestCase) {
+	s.TestCases = append(s.TestCases, testCase)
+	s.Tests++
+	if testCase.Failure != nil {
+		s.Failures++
+	}
+}
+
+func writeJUnit(out io.Writer, suites []junitTestSuite) error {
+	result := junitTestSuites{Name: "fromage", Suites: suites}
+	for _, suite := range suites {
+		result.Tests += suite.Tests
+		result.Failures += suite.Failures
+	}
+
+	if _, err := io.WriteString(out, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(out)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(result); err != nil {
+		return err
+	}
+	_, err := io.WriteString(out, "\n")
+	return err
+}
+
+// OutputJUnit writes the references as JUnit XML, with a test suite for every Dockerfile and a test case for
+// every reference. The test case fails if the reference is out of date.
+func (r DockerfileFromReferences) OutputJUnit(out io.Writer) error {
+	suites := make([]junitTestSuite, 0)
+	for _, file := range r.GroupByFile() {
+		suite := junitTestSuite{Name: file.Path, TestCases: make([]junitTestCase, 0, len(file.References))}
+		if file.Branch != "" {
+			suite.Name = fmt.Sprintf("%s:%s", file.Branch, file.Path)
+		}
+		for _, reference := range file.References {
+			suite.add(junitTestCase{
+				Name:      reference.Reference,
+				ClassName: suite.Name,
+				Failure:   reference.junitFailure(),
+			})
+		}
+		suites = append(suites, suite)
+	}
+	return writeJUnit(out, suites)
+}
+
+// OutputOnlyReferencesJUnit writes a single test suite with a test case for every distinct reference. The
+// test case fails if the reference is out of date in any of the Dockerfiles.
+func (r DockerfileFromReferences) OutputOnlyReferencesJUnit(out io.Writer) error {
+	suite := junitTestSuite{Name: "references", TestCases: make([]junitTestCase, 0)}
+	for _, name := range r.ExtractReferences() {
+		testCase := junitTestCase{Name: name, ClassName: suite.Name}
+		for _, reference := range r {
+			if reference.Reference == name && reference.failed() {
+				testCase.Failure = reference.junitFailure()
+				break
+			}
+		}
+		suite.add(testCase)
+	}
+	return writeJUnit(out, []junitTestSuite{suite})
+}
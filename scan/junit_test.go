@@ -0,0 +1,49 @@
+package scan
+
+import (
+	"bytes"
+	"encoding/xml"
+	"testing"
+)
+
+var testReferences = DockerfileFromReferences{
+	{Repository: "https://github.com/binxio/kritis", Commit: "abc123", Reference: "golang:1.12", Path: "deploy/Dockerfile", Line: 1, Branch: "main", Newer: []string{"1.13", "1.14"}},
+	{Repository: "https://github.com/binxio/kritis", Commit: "abc123", Reference: "alpine:3.16", Path: "deploy/Dockerfile", Line: 5, Branch: "main"},
+	{Repository: "https://github.com/binxio/kritis", Commit: "abc123", Reference: "golang:1.12", Path: "Dockerfile", Line: 1, Branch: "main"},
+}
+
+func TestOutputJUnit(t *testing.T) {
+	var out bytes.Buffer
+	if err := testReferences.Output(&out, "junit", false); err != nil {
+		t.Fatal(err)
+	}
+
+	var result junitTestSuites
+	if err := xml.Unmarshal(out.Bytes(), &result); err != nil {
+		t.Fatal(err)
+	}
+	if result.Tests != 3 || result.Failures != 1 || len(result.Suites) != 2 {
+		t.Fatalf("expected 3 tests, 1 failure in 2 suites, got %s", out.String())
+	}
+	if result.Suites[0].Name != "main:deploy/Dockerfile" {
+		t.Fatalf("expected suite main:deploy/Dockerfile, got %s", result.Suites[0].Name)
+	}
+	if failure := result.Suites[0].TestCases[0].Failure; failure == nil || failure.Message != "newer versions available: 1.13, 1.14" {
+		t.Fatalf("expected golang:1.12 to fail with newer versions, got %v", failure)
+	}
+}
+
+func TestOutputOnlyReferencesJUnit(t *testing.T) {
+	var out bytes.Buffer
+	if err := testReferences.OutputOnlyReferences(&out, "junit", false); err != nil {
+		t.Fatal(err)
+	}
+
+	var result junitTestSuites
+	if err := xml.Unmarshal(out.Bytes(), &result); err != nil {
+		t.Fatal(err)
+	}
+	if result.Tests != 2 || result.Failures != 1 {
+		t.Fatalf("expected 2 tests and 1 failure, got %s", out.String())
+	}
+}
@@ -0,0 +1,69 @@
+package scan
+
+import (
+	"context"
+	"github.com/binxio/fromage/dockerfile"
+	"github.com/binxio/fromage/policy"
+	"github.com/binxio/fromage/repository"
+	"strings"
+)
+
+// LintReferences returns the container image references of all Dockerfiles in the branches of the repository,
+// with the rules of the policy they violate. The registries are not consulted.
+func LintReferences(ctx context.Context, r *repository.Repository, branches []string, p *policy.Policy) (DockerfileFromReferences, error) {
+	return forEachBranchReference(ctx, r, branches, func(content []byte, branch string, filename string) DockerfileFromReferences {
+		return LintDockerfile(content, branch, filename, p)
+	})
+}
+
+// LintDockerfile returns the container image references in the Dockerfile content, with the rules of the
+// policy they violate.
+func LintDockerfile(content []byte, branch string, filename string, p *policy.Policy) DockerfileFromReferences {
+	result := make(DockerfileFromReferences, 0)
+	stages := dockerfile.ParseStages(content)
+	for _, statement := range dockerfile.ExtractFromReferences(content) {
+		reference := newReference(statement, stages, branch, filename)
+		if violations := p.Evaluate(statement.Reference); len(violations) > 0 {
+			reference.Violations = violations
+		}
+		result = append(result, reference)
+	}
+	return result
+}
+
+// FilterViolations returns the references which violate the policy.
+func (r DockerfileFromReferences) FilterViolations() DockerfileFromReferences {
+	result := make(DockerfileFromReferences, 0, len(r))
+	for _, reference := range r {
+		if len(reference.Violations) > 0 {
+			result = append(result, reference)
+		}
+	}
+	return result
+}
+
+// HasViolations returns true if any of the references violates the policy.
+func (r DockerfileFromReferences) HasViolations() bool {
+	return len(r.FilterViolations()) > 0
+}
+
+// violations returns the violated rules for the text output, or "-" if there are none.
+func (r DockerfileFromReference) violations() string {
+	if len(r.Violations) == 0 {
+		return "-"
+	}
+	rules := make([]string, 0, len(r.Violations))
+	for _, violation := range r.Violations {
+		rules = append(rules, violation.Rule)
+	}
+	return strings.Join(rules, ",")
+}
+
+// violationMessages returns the messages of the violated rules.
+func (r DockerfileFromReference) violationMessages() []string {
+	result := make([]string, 0, len(r.Violations))
+	for _, violation := range r.Violations {
+		result = append(result, violation.Message)
+	}
+	return result
+}
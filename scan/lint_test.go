@@ -0,0 +1,51 @@
+package scan
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/binxio/fromage/policy"
+	"strings"
+	"testing"
+)
+
+func TestLintDockerfile(t *testing.T) {
+	p, err := policy.Parse([]byte(`
+allowed-registries: [harbor.corp]
+forbidden-tags: [latest]
+forbid-untagged: true
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	content := []byte("FROM harbor.corp/base/golang:1.21 AS builder\nFROM ubuntu\nFROM harbor.corp/base/static:latest\n")
+	references := LintDockerfile(content, "main", "Dockerfile", p).FilterViolations()
+	if len(references) != 2 {
+		t.Fatalf("expected 2 references violating the policy, got %v", references.ExtractReferences())
+	}
+	if references[0].Reference != "ubuntu" || references[0].violations() != "disallowed-registry,untagged,forbidden-tag" {
+		t.Fatalf("expected ubuntu to violate 3 rules, got %s", references[0].violations())
+	}
+	if references[1].Line != 3 || references[1].violations() != "forbidden-tag" {
+		t.Fatalf("expected the latest tag on line 3 to be forbidden, got %s on %d", references[1].violations(), references[1].Line)
+	}
+
+	var out bytes.Buffer
+	if err = references.Output(&out, "json", false); err != nil {
+		t.Fatal(err)
+	}
+	var result []map[string]interface{}
+	if err = json.Unmarshal(out.Bytes(), &result); err != nil {
+		t.Fatal(err)
+	}
+	if violations, ok := result[1]["violations"].([]interface{}); !ok || len(violations) != 1 {
+		t.Fatalf("expected the violations in the JSON output, got %s", out.String())
+	}
+
+	out.Reset()
+	if err = references.Output(&out, "junit", false); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), `type="policy-violation"`) {
+		t.Fatalf("expected policy violations in the JUnit output, got %s", out.String())
+	}
+}
@@ -0,0 +1,83 @@
+package scan
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+func markdownLink(text, url string) string {
+	if url == "" {
+		return text
+	}
+	return fmt.Sprintf("[%s](%s)", text, url)
+}
+
+func markdownList(values []string) string {
+	if len(values) == 0 {
+		return "-"
+	}
+	return strings.Join(values, ", ")
+}
+
+// OutputMarkdown writes the references as Markdown tables, grouped by branch and Dockerfile. If the
+// repository is hosted on GitHub, GitLab or Bitbucket, the Dockerfiles and lines link to the scanned commit.
+func (r DockerfileFromReferences) OutputMarkdown(out io.Writer) error {
+	missingPlatforms := r.HasMissingPlatforms()
+	violations := r.HasViolations()
+	files := r.GroupByFile()
+	repository, branch := "", ""
+	for i, file := range files {
+		if i == 0 || file.Repository != repository || file.Branch != branch {
+			repository, branch = file.Repository, file.Branch
+			title := fmt.Sprintf("branch `%s`", branch)
+			if branch == "" {
+				title = "worktree"
+			}
+			if repository != "" {
+				title = fmt.Sprintf("%s %s", repository, title)
+			}
+			fmt.Fprintf(out, "## %s\n\n", title)
+		}
+
+		fmt.Fprintf(out, "### %s\n\n", markdownLink(file.Path, file.FileUrl()))
+		fmt.Fprintf(out, "| Image | Line | Newer |")
+		if missingPlatforms {
+			fmt.Fprintf(out, " Missing platforms |")
+		}
+		if violations {
+			fmt.Fprintf(out, " Violations |")
+		}
+		fmt.Fprintf(out, "\n| --- | --- | --- |")
+		if missingPlatforms {
+			fmt.Fprintf(out, " --- |")
+		}
+		if violations {
+			fmt.Fprintf(out, " --- |")
+		}
+		fmt.Fprintln(out)
+
+		for _, reference := range file.References {
+			fmt.Fprintf(out, "| `%s` | %s | %s |", reference.Reference,
+				markdownLink(fmt.Sprintf("%d", reference.Line), reference.FileUrl()), markdownList(reference.Newer))
+			if missingPlatforms {
+				fmt.Fprintf(out, " %s |", markdownList(reference.MissingPlatforms))
+			}
+			if violations {
+				fmt.Fprintf(out, " %s |", markdownList(reference.violationMessages()))
+			}
+			fmt.Fprintln(out)
+		}
+		fmt.Fprintln(out)
+	}
+	return nil
+}
+
+// OutputOnlyReferencesMarkdown writes the distinct references as a Markdown table.
+func (r DockerfileFromReferences) OutputOnlyReferencesMarkdown(out io.Writer) error {
+	fmt.Fprintf(out, "| Reference |\n| --- |\n")
+	for _, reference := range r.ExtractReferences() {
+		fmt.Fprintf(out, "| `%s` |\n", reference)
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+package scan
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestOutputMarkdown(t *testing.T) {
+	var out bytes.Buffer
+	if err := testReferences.Output(&out, "markdown", false); err != nil {
+		t.Fatal(err)
+	}
+
+	expect := "## https://github.com/binxio/kritis branch `main`\n" +
+		"\n" +
+		"### [deploy/Dockerfile](https://github.com/binxio/kritis/blob/abc123/deploy/Dockerfile)\n" +
+		"\n" +
+		"| Image | Line | Newer |\n" +
+		"| --- | --- | --- |\n" +
+		"| `golang:1.12` | [1](https://github.com/binxio/kritis/blob/abc123/deploy/Dockerfile#L1) | 1.13, 1.14 |\n" +
+		"| `alpine:3.16` | [5](https://github.com/binxio/kritis/blob/abc123/deploy/Dockerfile#L5) | - |\n" +
+		"\n" +
+		"### [Dockerfile](https://github.com/binxio/kritis/blob/abc123/Dockerfile)\n" +
+		"\n" +
+		"| Image | Line | Newer |\n" +
+		"| --- | --- | --- |\n" +
+		"| `golang:1.12` | [1](https://github.com/binxio/kritis/blob/abc123/Dockerfile#L1) | - |\n" +
+		"\n"
+	if out.String() != expect {
+		t.Fatalf("expected\n%s\ngot:\n%s", expect, out.String())
+	}
+}
+
+func TestOutputOnlyReferencesMarkdown(t *testing.T) {
+	var out bytes.Buffer
+	if err := testReferences.OutputOnlyReferences(&out, "markdown", false); err != nil {
+		t.Fatal(err)
+	}
+
+	expect := "| Reference |\n| --- |\n| `alpine:3.16` |\n| `golang:1.12` |\n"
+	if out.String() != expect {
+		t.Fatalf("expected\n%s\ngot:\n%s", expect, out.String())
+	}
+}
@@ -0,0 +1,334 @@
+package scan
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/binxio/fromage/policy"
+	"github.com/binxio/fromage/repository"
+	"github.com/binxio/fromage/tag"
+	"gopkg.in/yaml.v3"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+type DockerfileFromReference struct {
+	Repository        string             `json:"repository,omitempty" yaml:"repository,omitempty"`
+	Commit            string             `json:"commit,omitempty" yaml:"commit,omitempty"`
+	Reference         string             `json:"image,omitempty" yaml:"image"`
+	Path              string             `json:"path,omitempty"`
+	Line              int                `json:"line,omitempty" yaml:"line,omitempty"`
+	Column            int                `json:"column,omitempty" yaml:"column,omitempty"`
+	Branch            string             `json:"branch,omitempty"`
+	RefType           string             `json:"ref-type,omitempty" yaml:"ref-type,omitempty"`
+	Stage             string             `json:"stage,omitempty" yaml:"stage,omitempty"`
+	FinalStage        bool               `json:"final-stage" yaml:"final-stage"`
+	BuilderOnly       bool               `json:"builder-only,omitempty" yaml:"builder-only,omitempty"`
+	Stages            []string           `json:"stages,omitempty" yaml:"stages,omitempty"`
+	Newer             []string           `json:"newer,omitempty"`
+	LatestPatch       string             `json:"latest-patch,omitempty" yaml:"latest-patch,omitempty"`
+	LatestMinor       string             `json:"latest-minor,omitempty" yaml:"latest-minor,omitempty"`
+	LatestMajor       string             `json:"latest-major,omitempty" yaml:"latest-major,omitempty"`
+	LatestVariant     string             `json:"latest-variant,omitempty" yaml:"latest-variant,omitempty"`
+	UpdateType        string             `json:"update-type,omitempty" yaml:"update-type,omitempty"`
+	MissingPlatforms  []string           `json:"missing-platforms,omitempty" yaml:"missing-platforms,omitempty"`
+	Created           *time.Time         `json:"created,omitempty" yaml:"created,omitempty"`
+	Age               int                `json:"age-days,omitempty" yaml:"age-days,omitempty"`
+	NewestCreated     *time.Time         `json:"newest-created,omitempty" yaml:"newest-created,omitempty"`
+	NewestAge         int                `json:"newest-age-days,omitempty" yaml:"newest-age-days,omitempty"`
+	Behind            int                `json:"behind-days,omitempty" yaml:"behind-days,omitempty"`
+	Cycle             string             `json:"cycle,omitempty" yaml:"cycle,omitempty"`
+	EndOfLife         bool               `json:"end-of-life,omitempty" yaml:"end-of-life,omitempty"`
+	EndOfLifeDate     string             `json:"end-of-life-date,omitempty" yaml:"end-of-life-date,omitempty"`
+	Deprecated        bool               `json:"deprecated,omitempty" yaml:"deprecated,omitempty"`
+	DeprecationReason string             `json:"deprecation-reason,omitempty" yaml:"deprecation-reason,omitempty"`
+	Replacement       string             `json:"replacement,omitempty" yaml:"replacement,omitempty"`
+	Floating          bool               `json:"floating,omitempty" yaml:"floating,omitempty"`
+	Resolved          string             `json:"resolved,omitempty" yaml:"resolved,omitempty"`
+	Violations        []policy.Violation `json:"violations,omitempty" yaml:"violations,omitempty"`
+	RegistryError     string             `json:"registry-error,omitempty" yaml:"registry-error,omitempty"`
+}
+type DockerfileFromReferences []*DockerfileFromReference
+
+func (r DockerfileFromReferences) ExtractReferences() []string {
+	refs := make(map[string]bool, len(r))
+	for _, reference := range r {
+		refs[reference.Reference] = true
+	}
+	names := make([]string, 0, len(refs))
+	for name, _ := range refs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// FileUrl returns the web address of the reference in the Dockerfile at the scanned commit, if known.
+func (r DockerfileFromReference) FileUrl() string {
+	return repository.FileUrl(r.Repository, r.Commit, r.Path, r.Line)
+}
+
+func (r DockerfileFromReferences) OutputOnlyReferences(out io.Writer, format string, noHeader bool) error {
+	result := r.ExtractReferences()
+
+	if format == "json" {
+		encoder := json.NewEncoder(out)
+		return encoder.Encode(r)
+	} else if format == "yaml" {
+		encoder := yaml.NewEncoder(out)
+		return encoder.Encode(r)
+	} else if format == "sarif" {
+		// code scanning results require the location of the reference
+		return r.OutputSarif(out)
+	} else if format == "junit" {
+		return r.OutputOnlyReferencesJUnit(out)
+	} else if format == "markdown" {
+		return r.OutputOnlyReferencesMarkdown(out)
+	} else {
+		w := tabwriter.NewWriter(out, 1, 8, 0, '\t', tabwriter.TabIndent)
+		if !noHeader {
+			fmt.Fprintf(w, "%s\n", "REFERENCE")
+		}
+		for _, reference := range result {
+			fmt.Fprintf(w, "%s\n", reference)
+		}
+		return w.Flush()
+	}
+}
+
+func (r DockerfileFromReferences) Output(out io.Writer, format string, noHeader bool) error {
+
+	if format == "json" {
+		encoder := json.NewEncoder(out)
+		return encoder.Encode(r)
+	} else if format == "yaml" {
+		encoder := yaml.NewEncoder(out)
+		return encoder.Encode(r)
+	} else if format == "sarif" {
+		return r.OutputSarif(out)
+	} else if format == "junit" {
+		return r.OutputJUnit(out)
+	} else if format == "markdown" {
+		return r.OutputMarkdown(out)
+	} else {
+		w := tabwriter.NewWriter(out, 1, 8, 1, '\t', tabwriter.TabIndent)
+		refTypes := r.HasRefTypes()
+		missingPlatforms := r.HasMissingPlatforms()
+		ages := r.HasAges()
+		cycles := r.HasReleaseCycles()
+		deprecated := r.HasDeprecated()
+		violations := r.HasViolations()
+		floating := r.HasFloating()
+		if !noHeader {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s", "IMAGE", "PATH", "BRANCH", "COMMIT", "STAGE", "FINAL", "NEWER", "UPDATE")
+			if refTypes {
+				fmt.Fprintf(w, "\t%s", "REF TYPE")
+			}
+			if missingPlatforms {
+				fmt.Fprintf(w, "\t%s", "MISSING PLATFORMS")
+			}
+			if ages {
+				fmt.Fprintf(w, "\t%s\t%s", "AGE", "BEHIND")
+			}
+			if cycles {
+				fmt.Fprintf(w, "\t%s", "EOL")
+			}
+			if deprecated {
+				fmt.Fprintf(w, "\t%s", "DEPRECATED")
+			}
+			if floating {
+				fmt.Fprintf(w, "\t%s", "FLOATING")
+			}
+			if violations {
+				fmt.Fprintf(w, "\t%s", "VIOLATIONS")
+			}
+			fmt.Fprintln(w)
+		}
+		for _, reference := range r {
+			var newer = "-"
+			if reference.Newer != nil {
+				newer = strings.Join(reference.Newer, ",")
+			}
+			var update = "-"
+			if reference.UpdateType != "" {
+				update = reference.UpdateType
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s", reference.Reference, reference.Position(), reference.Branch,
+				reference.ShortCommit(), reference.StageName(), reference.finalStage(), newer, update)
+			if refTypes {
+				fmt.Fprintf(w, "\t%s", reference.RefType)
+			}
+			if missingPlatforms {
+				var missing = "-"
+				if len(reference.MissingPlatforms) > 0 {
+					missing = strings.Join(reference.MissingPlatforms, ",")
+				}
+				fmt.Fprintf(w, "\t%s", missing)
+			}
+			if ages {
+				fmt.Fprintf(w, "\t%s\t%s", formatDays(reference.Age, reference.Created != nil),
+					formatDays(reference.Behind, reference.NewestCreated != nil))
+			}
+			if cycles {
+				fmt.Fprintf(w, "\t%s", reference.endOfLife())
+			}
+			if deprecated {
+				fmt.Fprintf(w, "\t%s", reference.deprecated())
+			}
+			if floating {
+				fmt.Fprintf(w, "\t%s", reference.floating())
+			}
+			if violations {
+				fmt.Fprintf(w, "\t%s", reference.violations())
+			}
+			fmt.Fprintln(w)
+		}
+		return w.Flush()
+	}
+}
+
+// HasRefTypes returns true if any reference was found at a tag or commit, rather than on a branch.
+func (r DockerfileFromReferences) HasRefTypes() bool {
+	for _, reference := range r {
+		if reference.RefType != "" && reference.RefType != "branch" {
+			return true
+		}
+	}
+	return false
+}
+
+// Position returns the path of the Dockerfile with the line and column of the reference, as path:line:column.
+func (r DockerfileFromReference) Position() string {
+	if r.Line == 0 {
+		return r.Path
+	}
+	return fmt.Sprintf("%s:%d:%d", r.Path, r.Line, r.Column)
+}
+
+// ShortCommit returns the abbreviated hash of the scanned commit, or "-" if it is not known.
+func (r DockerfileFromReference) ShortCommit() string {
+	if r.Commit == "" {
+		return "-"
+	}
+	if len(r.Commit) > 7 {
+		return r.Commit[:7]
+	}
+	return r.Commit
+}
+
+// StageName returns the alias of the build stage, or "-" if the stage has no alias.
+func (r DockerfileFromReference) StageName() string {
+	if r.Stage == "" {
+		return "-"
+	}
+	return r.Stage
+}
+
+// finalStage returns yes if the reference is the base of the final stage, builder if it is only used in
+// builder stages, and no otherwise.
+func (r DockerfileFromReference) finalStage() string {
+	if r.FinalStage {
+		return "yes"
+	}
+	if r.BuilderOnly {
+		return "builder"
+	}
+	return "no"
+}
+
+// HasMissingPlatforms returns true if any of the references is missing a required platform.
+func (r DockerfileFromReferences) HasMissingPlatforms() bool {
+	for _, ref := range r {
+		if len(ref.MissingPlatforms) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func (r DockerfileFromReferences) FilterOutOfDate() DockerfileFromReferences {
+	result := make(DockerfileFromReferences, 0, len(r))
+
+	for _, ref := range r {
+		if ref.IsOutOfDate() {
+			result = append(result, ref)
+		}
+	}
+	return result
+}
+
+// DockerfileReferences are the references found in a single Dockerfile on a branch.
+type DockerfileReferences struct {
+	Repository string
+	Branch     string
+	Commit     string
+	Path       string
+	References DockerfileFromReferences
+}
+
+// FileUrl returns the web address of the Dockerfile at the scanned commit, if known.
+func (d DockerfileReferences) FileUrl() string {
+	return repository.FileUrl(d.Repository, d.Commit, d.Path, 0)
+}
+
+// GroupByFile groups the references by repository, branch and Dockerfile, in order of appearance.
+func (r DockerfileFromReferences) GroupByFile() []*DockerfileReferences {
+	result := make([]*DockerfileReferences, 0)
+	index := make(map[string]*DockerfileReferences)
+	for _, reference := range r {
+		key := strings.Join([]string{reference.Repository, reference.Branch, reference.Path}, "\x00")
+		group, ok := index[key]
+		if !ok {
+			group = &DockerfileReferences{
+				Repository: reference.Repository,
+				Branch:     reference.Branch,
+				Commit:     reference.Commit,
+				Path:       reference.Path,
+				References: make(DockerfileFromReferences, 0),
+			}
+			index[key] = group
+			result = append(result, group)
+		}
+		group.References = append(group.References, reference)
+	}
+	return result
+}
+
+// Latest returns the latest newer version on the version level, or an empty string if there is none.
+func (r DockerfileFromReference) Latest(level tag.Level) string {
+	switch level {
+	case tag.MAJOR:
+		return r.LatestMajor
+	case tag.MINOR:
+		return r.LatestMinor
+	case tag.PATCH:
+		return r.LatestPatch
+	default:
+		return ""
+	}
+}
+
+// FilterByUpdateLevel returns the references for which a newer version is available on any of the version
+// levels, or which are missing a required platform, deprecated or floating. A newer variant of the same
+// version, like alpine3.19 for alpine3.18, is reported on the patch level.
+func (r DockerfileFromReferences) FilterByUpdateLevel(levels []tag.Level) DockerfileFromReferences {
+	result := make(DockerfileFromReferences, 0, len(r))
+	for _, ref := range r {
+		matches := len(ref.MissingPlatforms) > 0 || ref.Deprecated || ref.Floating
+		for _, level := range levels {
+			matches = matches || ref.Latest(level) != "" || (level == tag.PATCH && ref.LatestVariant != "")
+		}
+		if matches {
+			result = append(result, ref)
+		}
+	}
+	return result
+}
+
+// IsOutOfDate returns true if a newer version is available, a required platform is missing or the
+// reference is deprecated or floating.
+func (r DockerfileFromReference) IsOutOfDate() bool {
+	return len(r.Newer) > 0 || len(r.MissingPlatforms) > 0 || r.Deprecated || r.Floating
+}
@@ -0,0 +1,83 @@
+package scan
+
+import (
+	"bytes"
+	"github.com/binxio/fromage/tag"
+	"strings"
+	"testing"
+)
+
+func TestFilterByUpdateLevel(t *testing.T) {
+	references := DockerfileFromReferences{
+		{Reference: "golang:1.12.1", Newer: []string{"1.12.2", "2.0.0"}, LatestPatch: "1.12.2", LatestMajor: "2.0.0", UpdateType: "major"},
+		{Reference: "alpine:3.16", Newer: []string{"3.17"}, LatestMinor: "3.17", UpdateType: "minor"},
+		{Reference: "ubuntu:22.04", MissingPlatforms: []string{"linux/s390x"}},
+		{Reference: "node:20-alpine3.18", Newer: []string{"20-alpine3.19"}, LatestVariant: "20-alpine3.19", UpdateType: "variant"},
+	}
+
+	var tests = []struct {
+		levels []tag.Level
+		expect []string
+	}{
+		{[]tag.Level{tag.PATCH}, []string{"golang:1.12.1", "ubuntu:22.04", "node:20-alpine3.18"}},
+		{[]tag.Level{tag.MINOR}, []string{"alpine:3.16", "ubuntu:22.04"}},
+		{[]tag.Level{tag.MINOR, tag.MAJOR}, []string{"golang:1.12.1", "alpine:3.16", "ubuntu:22.04"}},
+	}
+	for _, test := range tests {
+		result := references.FilterByUpdateLevel(test.levels)
+		if len(result) != len(test.expect) {
+			t.Fatalf("expected %v for %v, got %d references", test.expect, test.levels, len(result))
+		}
+		for i, reference := range result {
+			if reference.Reference != test.expect[i] {
+				t.Fatalf("expected %v for %v, got %s at %d", test.expect, test.levels, reference.Reference, i)
+			}
+		}
+	}
+}
+
+func TestUpdateType(t *testing.T) {
+	latest := tag.LatestByLevel(tag.MakeTag("1.12.1"), tag.Tags{tag.MakeTag("1.12.2"), tag.MakeTag("1.13.0")})
+	if result := updateType(latest, tag.Tag{}); result != "minor" {
+		t.Fatalf("expected minor, got %s", result)
+	}
+	current := tag.MakeTag("1.21-alpine3.18")
+	successors := tag.Tags{tag.MakeTag("1.21-alpine3.19"), tag.MakeTag("1.22-alpine3.19")}
+	variant, _ := tag.LatestVariant(current, successors)
+	if result := updateType(tag.LatestByLevel(current, successors[:1]), variant); result != "variant" {
+		t.Fatalf("expected variant, got %s", result)
+	}
+	if result := updateType(tag.LatestByLevel(current, successors), variant); result != "minor" {
+		t.Fatalf("expected minor, got %s", result)
+	}
+	if result := updateType(map[tag.Level]tag.Tag{}, tag.Tag{}); result != "" {
+		t.Fatalf("expected no update type, got %s", result)
+	}
+}
+
+func TestOutputText(t *testing.T) {
+	references := DockerfileFromReferences{
+		{Reference: "golang:1.12", Path: "Dockerfile", Line: 1, Column: 6, Branch: "main",
+			Commit: "4b825dc642cb6eb9a060e54bf8d69288fbee4904", Stage: "builder", BuilderOnly: true},
+		{Reference: "alpine:3.16", Path: "Dockerfile", Line: 3, Column: 6, Branch: "main",
+			Commit: "4b825dc642cb6eb9a060e54bf8d69288fbee4904", FinalStage: true, Newer: []string{"3.17"}, UpdateType: "minor"},
+	}
+	var out bytes.Buffer
+	if err := references.Output(&out, "text", false); err != nil {
+		t.Fatal(err)
+	}
+	expect := [][]string{
+		{"IMAGE", "PATH", "BRANCH", "COMMIT", "STAGE", "FINAL", "NEWER", "UPDATE"},
+		{"golang:1.12", "Dockerfile:1:6", "main", "4b825dc", "builder", "builder", "-", "-"},
+		{"alpine:3.16", "Dockerfile:3:6", "main", "4b825dc", "-", "yes", "3.17", "minor"},
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != len(expect) {
+		t.Fatalf("expected %d lines, got\n%s", len(expect), out.String())
+	}
+	for i, line := range lines {
+		if fields := strings.Fields(line); strings.Join(fields, " ") != strings.Join(expect[i], " ") {
+			t.Fatalf("expected %v, got %v", expect[i], fields)
+		}
+	}
+}
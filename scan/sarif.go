@@ -0,0 +1,307 @@
+package scan
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/binxio/fromage/policy"
+	"github.com/google/go-containerregistry/pkg/name"
+	"io"
+	"strings"
+)
+
+// The subset of the SARIF 2.1.0 format required to report the out-of-date references,
+// see https://docs.oasis-open.org/sarif/sarif/v2.1.0/sarif-v2.1.0.html.
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationUri string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	Id               string       `json:"id"`
+	Name             string       `json:"name"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+	HelpUri          string       `json:"helpUri,omitempty"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleId     string            `json:"ruleId"`
+	Level      string            `json:"level"`
+	Message    sarifMessage      `json:"message"`
+	Locations  []sarifLocation   `json:"locations"`
+	Fixes      []sarifFix        `json:"fixes,omitempty"`
+	Properties map[string]string `json:"properties,omitempty"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           sarifRegion           `json:"region"`
+}
+
+type sarifArtifactLocation struct {
+	Uri string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn"`
+	EndColumn   int `json:"endColumn"`
+}
+
+type sarifFix struct {
+	Description     sarifMessage          `json:"description"`
+	ArtifactChanges []sarifArtifactChange `json:"artifactChanges"`
+}
+
+type sarifArtifactChange struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Replacements     []sarifReplacement    `json:"replacements"`
+}
+
+type sarifReplacement struct {
+	DeletedRegion   sarifRegion  `json:"deletedRegion"`
+	InsertedContent sarifMessage `json:"insertedContent"`
+}
+
+const (
+	outOfDateRuleId       = "out-of-date-reference"
+	missingPlatformRuleId = "missing-platform"
+	endOfLifeRuleId       = "end-of-life"
+	deprecatedRuleId      = "deprecated-image"
+	floatingRuleId        = "floating-reference"
+	fromageInformationUri = "https://github.com/binxio/fromage"
+)
+
+var sarifRules = []sarifRule{
+	{
+		Id:               outOfDateRuleId,
+		Name:             "OutOfDateReference",
+		ShortDescription: sarifMessage{"A newer version of the container image is available"},
+		HelpUri:          fromageInformationUri,
+	},
+	{
+		Id:               missingPlatformRuleId,
+		Name:             "MissingPlatform",
+		ShortDescription: sarifMessage{"The container image is not available for all required platforms"},
+		HelpUri:          fromageInformationUri,
+	},
+	{
+		Id:               deprecatedRuleId,
+		Name:             "DeprecatedImage",
+		ShortDescription: sarifMessage{"The container image repository or tag is deprecated"},
+		HelpUri:          fromageInformationUri,
+	},
+	{
+		Id:               floatingRuleId,
+		Name:             "FloatingReference",
+		ShortDescription: sarifMessage{"The container image reference has no tag or a tag without version"},
+		HelpUri:          fromageInformationUri,
+	},
+	{
+		Id:               endOfLifeRuleId,
+		Name:             "EndOfLife",
+		ShortDescription: sarifMessage{"The release cycle of the container image is past its end-of-life"},
+		HelpUri:          "https://endoflife.date",
+	},
+	{
+		Id:               policy.DisallowedRegistry,
+		Name:             "DisallowedRegistry",
+		ShortDescription: sarifMessage{"The container image is not from an allowed registry"},
+		HelpUri:          fromageInformationUri,
+	},
+	{
+		Id:               policy.ForbiddenTag,
+		Name:             "ForbiddenTag",
+		ShortDescription: sarifMessage{"The container image uses a forbidden tag"},
+		HelpUri:          fromageInformationUri,
+	},
+	{
+		Id:               policy.Untagged,
+		Name:             "Untagged",
+		ShortDescription: sarifMessage{"The container image reference has no tag"},
+		HelpUri:          fromageInformationUri,
+	},
+	{
+		Id:               policy.MissingDigest,
+		Name:             "MissingDigest",
+		ShortDescription: sarifMessage{"The container image reference is not pinned by digest"},
+		HelpUri:          fromageInformationUri,
+	},
+	{
+		Id:               policy.InsufficientPin,
+		Name:             "InsufficientPin",
+		ShortDescription: sarifMessage{"The container image tag is not pinned on the required level"},
+		HelpUri:          fromageInformationUri,
+	},
+}
+
+// NewerReference returns the reference with the tag replaced by the newer version. A digest is dropped, as it
+// refers to the image of the current version. The registry is only included if the reference specifies it.
+func (r DockerfileFromReference) NewerReference(newer string) string {
+	ref, err := name.ParseReference(r.Reference)
+	if err != nil {
+		return r.Reference
+	}
+	repository := ref.Context().Name()
+	if !strings.HasPrefix(r.Reference, ref.Context().RegistryStr()+"/") {
+		repository = strings.TrimPrefix(ref.Context().RepositoryStr(), "library/")
+	}
+	return repository + ":" + newer
+}
+
+func (r DockerfileFromReference) sarifRegion() sarifRegion {
+	return sarifRegion{
+		StartLine:   r.Line,
+		StartColumn: r.Column,
+		EndColumn:   r.Column + len(r.Reference),
+	}
+}
+
+func (r DockerfileFromReference) sarifLocations() []sarifLocation {
+	return []sarifLocation{{
+		PhysicalLocation: sarifPhysicalLocation{
+			ArtifactLocation: sarifArtifactLocation{Uri: r.Path},
+			Region:           r.sarifRegion(),
+		},
+	}}
+}
+
+// sarifFix returns the fix replacing the reference with the replacement.
+func (r DockerfileFromReference) sarifFix(replacement string) sarifFix {
+	return sarifFix{
+		Description: sarifMessage{fmt.Sprintf("update %s to %s", r.Reference, replacement)},
+		ArtifactChanges: []sarifArtifactChange{{
+			ArtifactLocation: sarifArtifactLocation{Uri: r.Path},
+			Replacements: []sarifReplacement{{
+				DeletedRegion:   r.sarifRegion(),
+				InsertedContent: sarifMessage{replacement},
+			}},
+		}},
+	}
+}
+
+func (r DockerfileFromReference) sarifProperties() map[string]string {
+	if r.Branch == "" {
+		return nil
+	}
+	return map[string]string{"branch": r.Branch}
+}
+
+// sarifResults returns a result for every reference which is out of date, missing a required platform,
+// deprecated, floating, end-of-life or violating the policy.
+func (r DockerfileFromReferences) sarifResults() []sarifResult {
+	results := make([]sarifResult, 0, len(r))
+	for _, reference := range r {
+		if len(reference.Newer) > 0 {
+			next := reference.NewerReference(reference.Newer[0])
+			results = append(results, sarifResult{
+				RuleId: outOfDateRuleId,
+				Level:  "warning",
+				Message: sarifMessage{fmt.Sprintf("%s is out of date, newer versions are %s",
+					reference.Reference, strings.Join(reference.Newer, ", "))},
+				Locations:  reference.sarifLocations(),
+				Fixes:      []sarifFix{reference.sarifFix(next)},
+				Properties: reference.sarifProperties(),
+			})
+		}
+		if len(reference.MissingPlatforms) > 0 {
+			results = append(results, sarifResult{
+				RuleId: missingPlatformRuleId,
+				Level:  "error",
+				Message: sarifMessage{fmt.Sprintf("%s is not available for %s",
+					reference.Reference, strings.Join(reference.MissingPlatforms, ", "))},
+				Locations:  reference.sarifLocations(),
+				Properties: reference.sarifProperties(),
+			})
+		}
+		if reference.Deprecated {
+			result := sarifResult{
+				RuleId:     deprecatedRuleId,
+				Level:      "warning",
+				Message:    sarifMessage{fmt.Sprintf("%s is %s", reference.Reference, reference.deprecationMessage())},
+				Locations:  reference.sarifLocations(),
+				Properties: reference.sarifProperties(),
+			}
+			if reference.Replacement != "" {
+				result.Fixes = []sarifFix{reference.sarifFix(reference.Replacement)}
+			}
+			results = append(results, result)
+		}
+		if reference.Floating {
+			result := sarifResult{
+				RuleId:     floatingRuleId,
+				Level:      "warning",
+				Message:    sarifMessage{fmt.Sprintf("%s has a %s", reference.Reference, reference.floatingMessage())},
+				Locations:  reference.sarifLocations(),
+				Properties: reference.sarifProperties(),
+			}
+			if reference.Resolved != "" {
+				result.Fixes = []sarifFix{reference.sarifFix(reference.Resolved)}
+			}
+			results = append(results, result)
+		}
+		for _, violation := range reference.Violations {
+			results = append(results, sarifResult{
+				RuleId:     violation.Rule,
+				Level:      "error",
+				Message:    sarifMessage{violation.Message},
+				Locations:  reference.sarifLocations(),
+				Properties: reference.sarifProperties(),
+			})
+		}
+		if reference.EndOfLife {
+			results = append(results, sarifResult{
+				RuleId: endOfLifeRuleId,
+				Level:  "error",
+				Message: sarifMessage{fmt.Sprintf("%s is end-of-life, release cycle %s",
+					reference.Reference, reference.Cycle)},
+				Locations:  reference.sarifLocations(),
+				Properties: reference.sarifProperties(),
+			})
+		}
+	}
+	return results
+}
+
+// OutputSarif writes the out of date references in the SARIF format, so that they can be uploaded to
+// code scanning tools.
+func (r DockerfileFromReferences) OutputSarif(out io.Writer) error {
+	log := sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs: []sarifRun{{
+			Tool: sarifTool{Driver: sarifDriver{
+				Name:           "fromage",
+				InformationUri: fromageInformationUri,
+				Rules:          sarifRules,
+			}},
+			Results: r.sarifResults(),
+		}},
+	}
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(log)
+}
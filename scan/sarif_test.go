@@ -0,0 +1,72 @@
+package scan
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func TestNewerReference(t *testing.T) {
+	var tests = []struct {
+		reference string
+		newer     string
+		expect    string
+	}{
+		{"golang:1.12", "1.13", "golang:1.13"},
+		{"localhost:5000/golang:1.12", "1.13", "localhost:5000/golang:1.13"},
+		{"localhost:5000/golang", "1.13", "localhost:5000/golang:1.13"},
+		{"golang:1.12@sha256:3ab1b26d7a1aad6b6d8a7e8d1e5b7b7e1a9f0a1c2e1c0d7e0f8e9a0b1c2d3e4f", "1.13", "golang:1.13"},
+		{"localhost:5000/golang@sha256:3ab1b26d7a1aad6b6d8a7e8d1e5b7b7e1a9f0a1c2e1c0d7e0f8e9a0b1c2d3e4f", "1.13", "localhost:5000/golang:1.13"},
+		{"bitnami/redis:7.0", "7.2", "bitnami/redis:7.2"},
+		{"index.docker.io/library/golang:1.12", "1.13", "index.docker.io/library/golang:1.13"},
+	}
+	for _, test := range tests {
+		reference := DockerfileFromReference{Reference: test.reference}
+		if result := reference.NewerReference(test.newer); result != test.expect {
+			t.Fatalf("expected %s, got %s", test.expect, result)
+		}
+	}
+}
+
+func TestOutputSarif(t *testing.T) {
+	references := DockerfileFromReferences{
+		{Reference: "golang:1.12", Path: "deploy/Dockerfile", Line: 3, Column: 6, Branch: "main", Newer: []string{"1.13", "1.14"}},
+		{Reference: "alpine:3.16", Path: "Dockerfile", Line: 1, Column: 6, MissingPlatforms: []string{"linux/arm64"}},
+		{Reference: "ubuntu:22.04", Path: "Dockerfile", Line: 5, Column: 6},
+	}
+
+	var out bytes.Buffer
+	if err := references.Output(&out, "sarif", false); err != nil {
+		t.Fatal(err)
+	}
+
+	var log sarifLog
+	if err := json.Unmarshal(out.Bytes(), &log); err != nil {
+		t.Fatal(err)
+	}
+	if log.Version != "2.1.0" || len(log.Runs) != 1 {
+		t.Fatalf("expected a single SARIF 2.1.0 run, got %s", out.String())
+	}
+
+	results := log.Runs[0].Results
+	if len(results) != 2 {
+		t.Fatalf("expected 2 results, got %d", len(results))
+	}
+
+	outOfDate := results[0]
+	if outOfDate.RuleId != outOfDateRuleId {
+		t.Fatalf("expected rule %s, got %s", outOfDateRuleId, outOfDate.RuleId)
+	}
+	region := outOfDate.Locations[0].PhysicalLocation.Region
+	if region.StartLine != 3 || region.StartColumn != 6 || region.EndColumn != 17 {
+		t.Fatalf("expected region 3:6-17, got %v", region)
+	}
+	replacement := outOfDate.Fixes[0].ArtifactChanges[0].Replacements[0]
+	if replacement.InsertedContent.Text != "golang:1.13" {
+		t.Fatalf("expected fix golang:1.13, got %s", replacement.InsertedContent.Text)
+	}
+
+	if results[1].RuleId != missingPlatformRuleId {
+		t.Fatalf("expected rule %s, got %s", missingPlatformRuleId, results[1].RuleId)
+	}
+}
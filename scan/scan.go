@@ -0,0 +1,300 @@
+// Package scan lists the container image references in the Dockerfiles of a git repository, together
+// with the newer versions available.
+package scan
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"github.com/binxio/fromage/deprecation"
+	"github.com/binxio/fromage/dockerfile"
+	"github.com/binxio/fromage/eol"
+	"github.com/binxio/fromage/repository"
+	"github.com/binxio/fromage/tag"
+	"github.com/google/go-containerregistry/pkg/name"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+type Options struct {
+	Branches []string
+	// RefPatterns selects branches, tags and commits to scan instead of all branches, like refs/tags/v* or
+	// a commit hash. Only the Branches are scanned with them.
+	RefPatterns  []string
+	Pin          *tag.Level
+	VariantPin   *tag.Level
+	Platforms    tag.Platforms
+	EndOfLife    *eol.Dataset
+	Deprecations deprecation.Rules
+	FailOn       []tag.Level
+	MaxAge       time.Duration
+	// Ages retrieves the creation timestamps of the images to report their age, which are always
+	// retrieved with a MaxAge.
+	Ages      bool
+	FailOnEol bool
+	// State, if set, holds the results of previous scans, which are reused for branches whose tip did not
+	// change.
+	State *State
+}
+
+// fingerprint returns the options and paths which determine the references found, to record in the state.
+// The end-of-life dataset and deprecation rules are included by their digest.
+func (o Options) fingerprint(paths repository.PathFilter) string {
+	var pin, variantPin string
+	if o.Pin != nil {
+		pin = o.Pin.String()
+	}
+	if o.VariantPin != nil {
+		variantPin = o.VariantPin.String()
+	}
+	return fmt.Sprintf("pin=%s,pin-variant=%s,platforms=%v,paths=%s,eol=%s,deprecations=%s,max-age=%s,ages=%t",
+		pin, variantPin, o.Platforms, paths, hashOf(o.EndOfLife), hashOf(o.Deprecations), o.MaxAge, o.Ages)
+}
+
+// hashOf returns the abbreviated sha256 digest of the value in JSON, or an empty string if the value
+// cannot be encoded.
+func hashOf(value interface{}) string {
+	content, err := json.Marshal(value)
+	if err != nil {
+		return ""
+	}
+	return fmt.Sprintf("%x", sha256.Sum256(content))[0:12]
+}
+
+// ListReferences returns the container image references of all Dockerfiles in the branches of the repository.
+// With a State, branches whose tip did not change since the previous scan are not checked out, but the
+// references of the previous scan are returned.
+func ListReferences(ctx context.Context, r *repository.Repository, options Options) (DockerfileFromReferences, error) {
+	read := func(content []byte, branch string, filename string) DockerfileFromReferences {
+		return ReadReferences(content, branch, filename, options)
+	}
+	refs, err := selectRefs(ctx, r, options)
+	if err != nil {
+		return nil, err
+	}
+	if options.State == nil || r.IsWorktree() {
+		return forEachReference(ctx, r, refs, read)
+	}
+	return forEachChangedReference(ctx, r, refs, options, read)
+}
+
+// selectRefs returns the references to scan: the branches and the references matching the ref patterns, if
+// any, or else the branches.
+func selectRefs(ctx context.Context, r *repository.Repository, options Options) ([]*plumbing.Reference, error) {
+	if len(options.RefPatterns) == 0 {
+		return r.SelectBranches(options.Branches)
+	}
+	return r.ResolveRefs(ctx, append(append([]string{}, options.Branches...), options.RefPatterns...))
+}
+
+// forEachChangedReference returns the references found at the refs of the repository like forEachReference,
+// but only reads the refs whose commit changed since the scan recorded in the state. The state is recorded by
+// the full name of the ref.
+func forEachChangedReference(ctx context.Context, r *repository.Repository, refs []*plumbing.Reference, options Options,
+	read func(content []byte, branch string, filename string) DockerfileFromReferences) (DockerfileFromReferences, error) {
+	fingerprint := options.fingerprint(r.Paths)
+	byRef := make(map[string]DockerfileFromReferences)
+	changed := make([]*plumbing.Reference, 0)
+	for _, ref := range refs {
+		if references, ok := options.State.Lookup(r.Url, ref.Name().String(), ref.Hash().String(), fingerprint); ok {
+			log.Printf("INFO: %s of %s did not change since the previous scan", ref.Name().Short(), r.Url)
+			byRef[ref.Name().String()] = references
+		} else {
+			changed = append(changed, ref)
+		}
+	}
+
+	if len(changed) > 0 {
+		references, err := forEachReference(ctx, r, changed, read)
+		if err != nil {
+			return nil, err
+		}
+		for _, ref := range changed {
+			found := make(DockerfileFromReferences, 0)
+			for _, reference := range references {
+				if reference.Branch == ref.Name().Short() && reference.Commit == ref.Hash().String() {
+					found = append(found, reference)
+				}
+			}
+			byRef[ref.Name().String()] = found
+			options.State.Record(r.Url, ref.Name().String(), ref.Hash().String(), fingerprint, found)
+		}
+	}
+
+	existing, err := r.RefNames()
+	if err != nil {
+		return nil, err
+	}
+	for _, ref := range refs {
+		existing[ref.Name().String()] = true
+	}
+	options.State.Prune(r.Url, existing)
+	if err = options.State.Save(); err != nil {
+		log.Printf("WARNING: failed to save the state to %s, %s", options.State.Path, err)
+	}
+
+	result := make(DockerfileFromReferences, 0)
+	for _, ref := range refs {
+		result = append(result, byRef[ref.Name().String()]...)
+	}
+	return result, nil
+}
+
+// forEachBranchReference returns the container image references read from all Dockerfiles in the branches,
+// or all branches if none are specified.
+func forEachBranchReference(ctx context.Context, r *repository.Repository, branches []string,
+	read func(content []byte, branch string, filename string) DockerfileFromReferences) (DockerfileFromReferences, error) {
+	refs, err := r.SelectBranches(branches)
+	if err != nil {
+		return nil, err
+	}
+	return forEachReference(ctx, r, refs, read)
+}
+
+// parallelism is the number of Dockerfiles read concurrently.
+const parallelism = 8
+
+// forEachReference returns the container image references read from all Dockerfiles in the branches of
+// the repository, with the repository and commit in which they were found. The Dockerfiles are read
+// concurrently, and the references are returned in the order of the Dockerfiles.
+func forEachReference(ctx context.Context, r *repository.Repository, refs []*plumbing.Reference,
+	read func(content []byte, branch string, filename string) DockerfileFromReferences) (DockerfileFromReferences, error) {
+	dockerfiles, err := r.Dockerfiles(ctx, refs)
+	if err != nil {
+		return nil, err
+	}
+
+	found := make([]DockerfileFromReferences, len(dockerfiles))
+	work := make(chan int)
+	var workers sync.WaitGroup
+	for w := 0; w < parallelism && w < len(dockerfiles); w++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for i := range work {
+				found[i] = read(dockerfiles[i].Content, dockerfiles[i].Ref.Name().Short(), dockerfiles[i].Path)
+			}
+		}()
+	}
+	for i := range dockerfiles {
+		if ctx.Err() != nil {
+			break
+		}
+		work <- i
+	}
+	close(work)
+	workers.Wait()
+	if err = ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	result := make(DockerfileFromReferences, 0)
+	for i, references := range found {
+		ref := dockerfiles[i].Ref
+		for _, reference := range references {
+			reference.Repository = r.Url
+			if !ref.Hash().IsZero() {
+				reference.Commit = ref.Hash().String()
+			}
+			if !r.IsWorktree() {
+				reference.RefType = repository.RefType(ref)
+			}
+		}
+		result = append(result, references...)
+	}
+	return result, nil
+}
+
+// newReference returns the reference of the FROM statement, without any information from the registry.
+func newReference(statement dockerfile.FromStatement, stages []dockerfile.Stage, branch string, filename string) *DockerfileFromReference {
+	names := make([]string, 0)
+	for _, stage := range dockerfile.StagesOf(stages, statement.Reference) {
+		names = append(names, stage.String())
+	}
+	return &DockerfileFromReference{
+		Branch:      branch,
+		Path:        filename,
+		Line:        statement.Line,
+		Column:      statement.Column,
+		Stage:       statement.Alias,
+		FinalStage:  statement.Final,
+		BuilderOnly: statement.Builder,
+		Stages:      names,
+		Reference:   statement.Reference,
+	}
+}
+
+// updateType returns the most significant version level on which a newer version is available, or variant
+// if only a newer variant of the same version is available.
+func updateType(latest map[tag.Level]tag.Tag, variant tag.Tag) string {
+	for _, level := range []tag.Level{tag.MAJOR, tag.MINOR, tag.PATCH} {
+		if _, ok := latest[level]; ok {
+			return strings.ToLower(level.String())
+		}
+	}
+	if variant.Literal != "" {
+		return "variant"
+	}
+	return ""
+}
+
+// ReadReferences returns the container image references in the Dockerfile content.
+func ReadReferences(content []byte, branch string, filename string, options Options) DockerfileFromReferences {
+	result := make(DockerfileFromReferences, 0)
+	platforms := dockerfile.ExtractFromPlatforms(content)
+	stages := dockerfile.ParseStages(content)
+	for _, statement := range dockerfile.ExtractFromReferences(content) {
+		reference := statement.Reference
+		required := options.Platforms.Merge(platforms[reference])
+
+		var newer []string
+		var latest = map[tag.Level]tag.Tag{}
+		var variant tag.Tag
+		var registryError string
+		if successors, err := tag.GetAllSuccessorsByString(reference, options.Pin, options.VariantPin, required); err == nil {
+			newer = make([]string, 0, len(successors))
+			for _, v := range successors {
+				newer = append(newer, v.String())
+			}
+			if ref, err := name.NewTag(reference); err == nil {
+				latest = tag.LatestByLevel(tag.MakeTag(ref.TagStr()), successors)
+				variant, _ = tag.LatestVariant(tag.MakeTag(ref.TagStr()), successors)
+			}
+		} else if _, parseErr := name.ParseReference(reference); parseErr == nil {
+			registryError = err.Error()
+		}
+
+		var missing []string
+		if len(required) > 0 {
+			if ref, err := name.ParseReference(reference); err == nil {
+				if m, err := tag.GetMissingPlatforms(ref, required); err == nil {
+					missing = m.Strings()
+				} else {
+					log.Printf("WARNING: %s", err)
+				}
+			}
+		}
+
+		from := newReference(statement, stages, branch, filename)
+		from.Newer = newer
+		from.LatestPatch = latest[tag.PATCH].Literal
+		from.LatestMinor = latest[tag.MINOR].Literal
+		from.LatestMajor = latest[tag.MAJOR].Literal
+		from.LatestVariant = variant.Literal
+		from.UpdateType = updateType(latest, variant)
+		from.MissingPlatforms = missing
+		from.RegistryError = registryError
+		if options.Ages || options.MaxAge > 0 {
+			from.readCreated()
+		}
+		from.readEndOfLife(options.EndOfLife)
+		from.readDeprecation(options.Deprecations)
+		from.readFloating()
+		result = append(result, from)
+	}
+	return result
+}
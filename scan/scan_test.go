@@ -0,0 +1,90 @@
+package scan
+
+import (
+	"context"
+	"fmt"
+	"github.com/binxio/fromage/repository"
+	"github.com/google/go-containerregistry/pkg/registry"
+	"gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+	"io/ioutil"
+	"log"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestListReferencesWithRefPatterns(t *testing.T) {
+	server := httptest.NewServer(registry.New(registry.Logger(log.New(ioutil.Discard, "", 0))))
+	defer server.Close()
+	u, _ := url.Parse(server.URL)
+
+	dir, err := ioutil.TempDir("", "fromage-refs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	g, err := git.PlainInit(dir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	worktree, _ := g.Worktree()
+	commit := func(content string) plumbing.Hash {
+		if err := ioutil.WriteFile(filepath.Join(dir, "Dockerfile"), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := worktree.Add("Dockerfile"); err != nil {
+			t.Fatal(err)
+		}
+		hash, err := worktree.Commit("update Dockerfile", &git.CommitOptions{
+			Author: &object.Signature{Name: "test", Email: "test@example.com", When: time.Now()},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return hash
+	}
+	release := commit(fmt.Sprintf("FROM %s/library/alpine:3.17\n", u.Host))
+	if _, err = g.CreateTag("v1.0", release, nil); err != nil {
+		t.Fatal(err)
+	}
+	err = worktree.Checkout(&git.CheckoutOptions{Branch: plumbing.NewBranchReferenceName("develop"), Create: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	commit(fmt.Sprintf("FROM %s/library/alpine:3.18\n", u.Host))
+
+	r, err := repository.Open(context.Background(), dir, false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tests = []struct {
+		branches []string
+		patterns []string
+		expect   string
+	}{
+		{nil, nil, "develop,master"},
+		{nil, []string{"refs/tags/v*"}, "v1.0"},
+		{[]string{"develop"}, []string{"refs/tags/v*"}, "develop,v1.0"},
+	}
+	for _, test := range tests {
+		references, err := ListReferences(context.Background(), r, Options{Branches: test.branches, RefPatterns: test.patterns})
+		if err != nil {
+			t.Fatal(err)
+		}
+		refs := make([]string, 0, len(references))
+		for _, reference := range references {
+			refs = append(refs, reference.Branch)
+		}
+		sort.Strings(refs)
+		if result := strings.Join(refs, ","); result != test.expect {
+			t.Fatalf("expected %s to be scanned for branches %v and patterns %v, got %s", test.expect, test.branches, test.patterns, result)
+		}
+	}
+}
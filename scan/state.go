@@ -0,0 +1,102 @@
+package scan
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// StateMaxAge is the period after which the references of a branch are scanned again, even if its tip did
+// not change, so that new versions in the registries are picked up.
+const StateMaxAge = 24 * time.Hour
+
+// BranchState is the result of the scan of the tip of a branch.
+type BranchState struct {
+	Commit     string                   `json:"commit"`
+	Options    string                   `json:"options"`
+	Scanned    time.Time                `json:"scanned"`
+	References DockerfileFromReferences `json:"references"`
+}
+
+// State records the results of the scans of the branches of the repositories, so that branches whose tip
+// did not change are not scanned again.
+type State struct {
+	Path         string                             `json:"-"`
+	Repositories map[string]map[string]*BranchState `json:"repositories"`
+
+	mutex sync.Mutex
+}
+
+// LoadState reads the state from the file at path. If the file does not exist, the state is empty.
+func LoadState(path string) (*State, error) {
+	result := &State{Path: path, Repositories: make(map[string]map[string]*BranchState)}
+	content, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return result, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal(content, result); err != nil {
+		return nil, fmt.Errorf("failed to read the state from %s, %s", path, err)
+	}
+	if result.Repositories == nil {
+		result.Repositories = make(map[string]map[string]*BranchState)
+	}
+	return result, nil
+}
+
+// Save writes the state to its file.
+func (s *State) Save() error {
+	s.mutex.Lock()
+	content, err := json.Marshal(s)
+	s.mutex.Unlock()
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(s.Path), 0755); err != nil {
+		return err
+	}
+	tmp := s.Path + ".tmp"
+	if err = ioutil.WriteFile(tmp, content, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.Path)
+}
+
+// Lookup returns the references found on the branch of the repository, if the branch was scanned at the
+// commit with the same options less than StateMaxAge ago.
+func (s *State) Lookup(url string, branch string, commit string, options string) (DockerfileFromReferences, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	state, ok := s.Repositories[url][branch]
+	if !ok || state.Commit != commit || state.Options != options || time.Since(state.Scanned) > StateMaxAge {
+		return nil, false
+	}
+	return state.References, true
+}
+
+// Record records the references found on the branch of the repository at the commit.
+func (s *State) Record(url string, branch string, commit string, options string, references DockerfileFromReferences) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.Repositories[url] == nil {
+		s.Repositories[url] = make(map[string]*BranchState)
+	}
+	s.Repositories[url][branch] = &BranchState{Commit: commit, Options: options, Scanned: time.Now(), References: references}
+}
+
+// Prune removes the branches of the repository which no longer exist.
+func (s *State) Prune(url string, branches map[string]bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for branch := range s.Repositories[url] {
+		if !branches[branch] {
+			delete(s.Repositories[url], branch)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package scan
+
+import (
+	"context"
+	"fmt"
+	"github.com/binxio/fromage/deprecation"
+	"github.com/binxio/fromage/eol"
+	"github.com/binxio/fromage/repository"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+	"io/ioutil"
+	"log"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestState(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fromage-state")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "cache", "state.json")
+
+	state, err := LoadState(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	references := DockerfileFromReferences{{Reference: "alpine:3.17", Branch: "main", Path: "Dockerfile"}}
+	state.Record("https://github.com/binxio/kritis", "main", "abc", "pin=", references)
+	if err = state.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	if state, err = LoadState(path); err != nil {
+		t.Fatal(err)
+	}
+	var tests = []struct {
+		branch, commit, options string
+		found                   bool
+	}{
+		{"main", "abc", "pin=", true},
+		{"main", "def", "pin=", false},
+		{"main", "abc", "pin=MINOR", false},
+		{"develop", "abc", "pin=", false},
+	}
+	for _, test := range tests {
+		result, ok := state.Lookup("https://github.com/binxio/kritis", test.branch, test.commit, test.options)
+		if ok != test.found {
+			t.Fatalf("expected %s at %s with %s found %v, got %v", test.branch, test.commit, test.options, test.found, ok)
+		}
+		if ok && (len(result) != 1 || result[0].Reference != "alpine:3.17") {
+			t.Fatalf("expected the recorded references, got %v", result.ExtractReferences())
+		}
+	}
+
+	state.Repositories["https://github.com/binxio/kritis"]["main"].Scanned = time.Now().Add(-StateMaxAge - time.Minute)
+	if _, ok := state.Lookup("https://github.com/binxio/kritis", "main", "abc", "pin="); ok {
+		t.Fatalf("expected a scan older than %s not to be reused", StateMaxAge)
+	}
+
+	state.Prune("https://github.com/binxio/kritis", map[string]bool{"develop": true})
+	if len(state.Repositories["https://github.com/binxio/kritis"]) != 0 {
+		t.Fatalf("expected the deleted branch to be pruned")
+	}
+}
+
+func TestListReferencesWithState(t *testing.T) {
+	server := httptest.NewServer(registry.New(registry.Logger(log.New(ioutil.Discard, "", 0))))
+	defer server.Close()
+	u, _ := url.Parse(server.URL)
+	image, err := random.Image(64, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ref, _ := name.ParseReference(fmt.Sprintf("%s/library/alpine:3.17", u.Host))
+	if err = remote.Write(ref, image); err != nil {
+		t.Fatal(err)
+	}
+
+	dir, err := ioutil.TempDir("", "fromage-state")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	g, err := git.PlainInit(dir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content := fmt.Sprintf("FROM %s/library/alpine:3.17\n", u.Host)
+	if err = ioutil.WriteFile(filepath.Join(dir, "Dockerfile"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	worktree, _ := g.Worktree()
+	if _, err = worktree.Add("Dockerfile"); err != nil {
+		t.Fatal(err)
+	}
+	_, err = worktree.Commit("add Dockerfile", &git.CommitOptions{
+		Author: &object.Signature{Name: "test", Email: "test@example.com", When: time.Now()},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	state, err := LoadState(filepath.Join(dir, "state.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := repository.Open(context.Background(), dir, true, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	references, err := ListReferences(context.Background(), r, Options{State: state})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(references) != 1 || references[0].Branch != "master" {
+		t.Fatalf("expected a reference on master, got %v", references.ExtractReferences())
+	}
+
+	// a branch whose tip did not change is not read again
+	state.Repositories[dir]["refs/heads/master"].References[0].Reference = "recorded"
+	if references, err = ListReferences(context.Background(), r, Options{State: state}); err != nil {
+		t.Fatal(err)
+	}
+	if len(references) != 1 || references[0].Reference != "recorded" {
+		t.Fatalf("expected the recorded references to be reused, got %v", references.ExtractReferences())
+	}
+
+	// the recorded references are not reused with other deprecation rules or end-of-life data
+	rules, err := deprecation.Parse([]byte("deprecations:\n  - image: alpine\n    reason: test\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dataset, err := eol.Parse([]byte(`{"images": {"alpine": "alpine"}, "products": {"alpine": []}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, options := range []Options{{Deprecations: rules}, {EndOfLife: dataset}, {MaxAge: 24 * time.Hour}} {
+		state.Repositories[dir]["refs/heads/master"].References[0].Reference = "recorded"
+		options.State = state
+		if references, err = ListReferences(context.Background(), r, options); err != nil {
+			t.Fatal(err)
+		}
+		if len(references) != 1 || references[0].Reference == "recorded" {
+			t.Fatalf("expected the references to be scanned again, got %v", references.ExtractReferences())
+		}
+	}
+	if _, err = os.Stat(state.Path); err != nil {
+		t.Fatalf("expected the state to be saved, %s", err)
+	}
+}
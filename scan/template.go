@@ -0,0 +1,75 @@
+package scan
+
+import (
+	"github.com/binxio/fromage/tag"
+	"github.com/google/go-containerregistry/pkg/name"
+	"io"
+	"io/ioutil"
+	"strings"
+	"text/template"
+)
+
+// templateFunctions are the helper functions available in an output template.
+var templateFunctions = template.FuncMap{
+	"latest":      latest,
+	"join":        join,
+	"semverLevel": semverLevel,
+}
+
+// latest returns the last of the newer versions, or an empty string if there are none.
+func latest(newer []string) string {
+	if len(newer) == 0 {
+		return ""
+	}
+	return newer[len(newer)-1]
+}
+
+func join(values []string, separator string) string {
+	return strings.Join(values, separator)
+}
+
+// semverLevel returns the version level, major, minor or patch, on which the tag of the
+// reference differs from the newer version, or variant if only the version of the variant differs. It
+// returns an empty string if it cannot be determined.
+func semverLevel(reference string, newer string) string {
+	r, err := name.ParseReference(reference)
+	if err != nil {
+		return ""
+	}
+	t, ok := r.(name.Tag)
+	if !ok || newer == "" {
+		return ""
+	}
+	current, next := tag.MakeTag(t.TagStr()), tag.MakeTag(newer)
+	if level, changed := tag.ChangedLevel(current, next); changed {
+		return strings.ToLower(level.String())
+	}
+	if current.IsComposite() && current.Category == next.Category && current.Compare(next) != 0 {
+		return "variant"
+	}
+	return ""
+}
+
+// ParseTemplate parses the text as output template.
+func ParseTemplate(text string) (*template.Template, error) {
+	return template.New("output").Funcs(templateFunctions).Parse(text)
+}
+
+// ParseTemplateFile parses the content of the file as output template.
+func ParseTemplateFile(filename string) (*template.Template, error) {
+	content, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return ParseTemplate(string(content))
+}
+
+// OutputTemplate renders the template over the references.
+func (r DockerfileFromReferences) OutputTemplate(out io.Writer, t *template.Template) error {
+	return t.Execute(out, r)
+}
+
+// OutputOnlyReferencesTemplate renders the template over the distinct references.
+func (r DockerfileFromReferences) OutputOnlyReferencesTemplate(out io.Writer, t *template.Template) error {
+	return t.Execute(out, r.ExtractReferences())
+}
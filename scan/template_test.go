@@ -0,0 +1,57 @@
+package scan
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestOutputTemplate(t *testing.T) {
+	tmpl, err := ParseTemplate(`{{range .}}{{.Reference}} -> {{latest .Newer}} {{semverLevel .Reference (latest .Newer)}} [{{join .Newer ","}}]{{"\n"}}{{end}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	if err = testReferences.OutputTemplate(&out, tmpl); err != nil {
+		t.Fatal(err)
+	}
+
+	expect := "golang:1.12 -> 1.14 minor [1.13,1.14]\nalpine:3.16 ->   []\ngolang:1.12 ->   []\n"
+	if out.String() != expect {
+		t.Fatalf("expected\n%s\ngot:\n%s", expect, out.String())
+	}
+}
+
+func TestOutputOnlyReferencesTemplate(t *testing.T) {
+	tmpl, err := ParseTemplate(`{{join . " "}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	if err = testReferences.OutputOnlyReferencesTemplate(&out, tmpl); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "alpine:3.16 golang:1.12" {
+		t.Fatalf("expected alpine:3.16 golang:1.12, got %s", out.String())
+	}
+}
+
+func TestSemverLevel(t *testing.T) {
+	var tests = []struct {
+		reference, newer, expect string
+	}{
+		{"golang:1.12", "2.0", "major"},
+		{"golang:1.12", "1.13", "minor"},
+		{"golang:1.12.1", "1.12.2", "patch"},
+		{"golang:1.21-alpine3.18", "1.21-alpine3.19", "variant"},
+		{"golang:1.21-alpine3.18", "1.22-alpine3.19", "minor"},
+		{"golang:1.12", "", ""},
+		{"golang@sha256:d0e79a9c39cdb3d71cc45fec929d1308d50420b79201467ec602b1b80cc314a8", "1.13", ""},
+	}
+	for _, test := range tests {
+		if result := semverLevel(test.reference, test.newer); result != test.expect {
+			t.Fatalf("expected %s -> %s to be %s, got %s", test.reference, test.newer, test.expect, result)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package scan
+
+import (
+	"context"
+	"fmt"
+	"github.com/binxio/fromage/dockerfile"
+	"github.com/binxio/fromage/repository"
+	"github.com/binxio/fromage/tag"
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"log"
+	"regexp"
+	"strings"
+)
+
+// ImageMatcher selects the references to an image. In exact mode, the reference must refer to the same
+// repository and tag or digest as the image. In prefix mode, the reference as written or fully qualified must
+// start with the image, and in regex mode it must match the image as regular expression. With ByDigest,
+// references which resolve to the same digest as the image also match.
+type ImageMatcher struct {
+	Image    string
+	Mode     string
+	ByDigest bool
+
+	reference name.Reference
+	pattern   *regexp.Regexp
+	digest    v1.Hash
+}
+
+// NewImageMatcher returns a matcher for the image in the mode: exact, prefix or regex. With byDigest, the
+// digest of the image is resolved.
+func NewImageMatcher(image string, mode string, byDigest bool) (*ImageMatcher, error) {
+	result := &ImageMatcher{Image: image, Mode: mode, ByDigest: byDigest}
+	var err error
+	switch mode {
+	case "", "exact":
+		result.Mode = "exact"
+		if result.reference, err = name.ParseReference(image); err != nil {
+			return nil, fmt.Errorf("%s is not a valid image reference, %s", image, err)
+		}
+	case "prefix":
+	case "regex":
+		if result.pattern, err = regexp.Compile(image); err != nil {
+			return nil, fmt.Errorf("%s is not a valid regular expression, %s", image, err)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported match %s, expected exact, prefix or regex", mode)
+	}
+
+	if byDigest {
+		reference, err := name.ParseReference(image)
+		if err != nil {
+			return nil, fmt.Errorf("%s is not a valid image reference to resolve, %s", image, err)
+		}
+		if result.digest, err = digestOf(reference); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// digestOf returns the digest of the reference, from the reference itself or else from the registry.
+func digestOf(reference name.Reference) (v1.Hash, error) {
+	if digest, ok := reference.(name.Digest); ok {
+		return v1.NewHash(digest.DigestStr())
+	}
+	return tag.GetDigest(reference)
+}
+
+// Matches returns true if the reference refers to the image.
+func (m *ImageMatcher) Matches(reference string) bool {
+	ref, err := name.ParseReference(reference)
+	if err != nil {
+		return m.Mode != "exact" && m.matchesText(reference, "")
+	}
+	var matches bool
+	if m.Mode == "exact" {
+		matches = ref.Name() == m.reference.Name()
+	} else {
+		matches = m.matchesText(reference, ref.Name())
+	}
+	if matches || !m.ByDigest {
+		return matches
+	}
+
+	digest, err := digestOf(ref)
+	if err != nil {
+		log.Printf("WARNING: %s", err)
+		return false
+	}
+	return digest == m.digest
+}
+
+func (m *ImageMatcher) matchesText(reference string, qualified string) bool {
+	if m.Mode == "regex" {
+		return m.pattern.MatchString(reference) || (qualified != "" && m.pattern.MatchString(qualified))
+	}
+	return strings.HasPrefix(reference, m.Image) || (qualified != "" && strings.HasPrefix(qualified, m.Image))
+}
+
+// FindReferences returns the container image references of all Dockerfiles in the branches of the repository
+// which refer to the image of the matcher. The registries are only consulted to match by digest.
+func FindReferences(ctx context.Context, r *repository.Repository, branches []string, m *ImageMatcher) (DockerfileFromReferences, error) {
+	return forEachBranchReference(ctx, r, branches, func(content []byte, branch string, filename string) DockerfileFromReferences {
+		result := make(DockerfileFromReferences, 0)
+		stages := dockerfile.ParseStages(content)
+		for _, statement := range dockerfile.ExtractFromReferences(content) {
+			if m.Matches(statement.Reference) {
+				result = append(result, newReference(statement, stages, branch, filename))
+			}
+		}
+		return result
+	})
+}
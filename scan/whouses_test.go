@@ -0,0 +1,115 @@
+package scan
+
+import (
+	"context"
+	"fmt"
+	"github.com/binxio/fromage/repository"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"io/ioutil"
+	"log"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestImageMatcher(t *testing.T) {
+	var tests = []struct {
+		image, mode, reference string
+		matches                bool
+	}{
+		{"alpine:3.17", "exact", "alpine:3.17", true},
+		{"alpine:3.17", "", "docker.io/library/alpine:3.17", true},
+		{"alpine:3.17", "exact", "alpine:3.17.2", false},
+		{"alpine:3.17", "prefix", "alpine:3.17.2", true},
+		{"index.docker.io/library/alpine:3", "prefix", "alpine:3.17", true},
+		{"alpine:3", "prefix", "myalpine:3.17", false},
+		{`^alpine:3\.1[67]`, "regex", "alpine:3.17", true},
+		{`^alpine:3\.1[67]`, "regex", "alpine:3.18", false},
+	}
+	for _, test := range tests {
+		m, err := NewImageMatcher(test.image, test.mode, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result := m.Matches(test.reference); result != test.matches {
+			t.Fatalf("expected %s to match %s %s %v, got %v", test.reference, test.mode, test.image, test.matches, result)
+		}
+	}
+
+	if _, err := NewImageMatcher("alpine", "glob", false); err == nil {
+		t.Fatalf("expected an error for an unsupported match")
+	}
+}
+
+func TestFindReferences(t *testing.T) {
+	server := httptest.NewServer(registry.New(registry.Logger(log.New(ioutil.Discard, "", 0))))
+	defer server.Close()
+	u, _ := url.Parse(server.URL)
+
+	image, err := random.Image(64, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	digest, err := image.Digest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, reference := range []string{"library/alpine:3.17", "library/alpine:3.17.2", "mirror/alpine:3.17"} {
+		ref, _ := name.ParseReference(fmt.Sprintf("%s/%s", u.Host, reference))
+		if err = remote.Write(ref, image); err != nil {
+			t.Fatal(err)
+		}
+	}
+	other, _ := random.Image(64, 1)
+	otherRef, _ := name.ParseReference(fmt.Sprintf("%s/library/alpine:3.18", u.Host))
+	if err = remote.Write(otherRef, other); err != nil {
+		t.Fatal(err)
+	}
+
+	dir, err := ioutil.TempDir("", "fromage-who-uses")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	content := fmt.Sprintf("FROM %[1]s/library/alpine:3.17.2 AS build\nFROM %[1]s/mirror/alpine@%[2]s\nFROM %[1]s/library/alpine:3.18\n",
+		u.Host, digest)
+	if err = ioutil.WriteFile(filepath.Join(dir, "Dockerfile"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	r, err := repository.OpenWorktree(dir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var tests = []struct {
+		byDigest bool
+		expect   []int
+	}{
+		{false, []int{}},
+		{true, []int{1, 2}},
+	}
+	for _, test := range tests {
+		m, err := NewImageMatcher(fmt.Sprintf("%s/library/alpine:3.17", u.Host), "exact", test.byDigest)
+		if err != nil {
+			t.Fatal(err)
+		}
+		result, err := FindReferences(context.Background(), r, nil, m)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(result) != len(test.expect) {
+			t.Fatalf("expected %d references matching by digest %v, got %v", len(test.expect), test.byDigest,
+				result.ExtractReferences())
+		}
+		for i, line := range test.expect {
+			if result[i].Line != line {
+				t.Fatalf("expected a reference on line %d, got %d", line, result[i].Line)
+			}
+		}
+	}
+}
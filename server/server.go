@@ -0,0 +1,264 @@
+// Package server checks the container image references in the Dockerfiles of a repository whenever a branch
+// is pushed, as announced by GitHub or GitLab webhooks.
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/binxio/fromage/repository"
+	"github.com/binxio/fromage/scan"
+	"github.com/binxio/fromage/tag"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxPayload is the maximum size of a webhook payload.
+const maxPayload = 25 * 1024 * 1024
+
+// queueSize is the number of pushes which may wait to be checked.
+const queueSize = 64
+
+// pendingDescription is the description of the result of a push which is not checked yet.
+const pendingDescription = "checking container image references"
+
+// State is the state of the check of a push.
+type State string
+
+const (
+	Pending State = "pending"
+	Success State = "success"
+	Failure State = "failure"
+	Error   State = "error"
+)
+
+// Result is the result of the check of a push. References are the references which fail the check.
+type Result struct {
+	Push
+	State       State                         `json:"state"`
+	Description string                        `json:"description"`
+	References  scan.DockerfileFromReferences `json:"references,omitempty"`
+	Error       string                        `json:"error,omitempty"`
+	Time        time.Time                     `json:"time"`
+}
+
+// shortDescription returns the description, truncated to the maximum length of a commit status.
+func (r *Result) shortDescription() string {
+	if len(r.Description) > maxDescription {
+		return r.Description[:maxDescription-3] + "..."
+	}
+	return r.Description
+}
+
+// Options configures the server. Secret verifies the webhooks, Scan configures the check like fromage check,
+// and Status, if set, receives the result of every check.
+type Options struct {
+	Secret string
+	Scan   scan.Options
+	Status StatusClient
+	// CacheDir, if set, holds the mirrors of the repositories, which are updated incrementally.
+	CacheDir string
+	Verbose  bool
+}
+
+// Server receives push webhooks on /webhook, checks the pushed branches one at a time, and serves the latest
+// result of every branch on /results.
+type Server struct {
+	options Options
+	queue   chan *Push
+	pending sync.WaitGroup
+	mutex   sync.Mutex
+	results map[string]*Result
+}
+
+// New returns a server with the options. Pushes are only checked while Run is active.
+func New(options Options) *Server {
+	return &Server{
+		options: options,
+		queue:   make(chan *Push, queueSize),
+		results: make(map[string]*Result),
+	}
+}
+
+// ServeHTTP handles the webhooks on /webhook and the requests for the results on /results.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/webhook":
+		s.handleWebhook(w, r)
+	case "/results":
+		s.handleResults(w, r)
+	case "/healthz":
+		w.WriteHeader(http.StatusOK)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *Server) handleWebhook(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxPayload))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	push, err := ParsePush(r.Header, body, s.options.Secret)
+	if err == errIgnored {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	if err != nil {
+		log.Printf("WARNING: rejected webhook from %s, %s", r.RemoteAddr, err)
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	// the commit status is posted by the worker, so slow status updates do not delay the webhook
+	s.recordResult(&Result{Push: *push, State: Pending, Description: pendingDescription})
+	s.pending.Add(1)
+	select {
+	case s.queue <- push:
+		w.WriteHeader(http.StatusAccepted)
+	default:
+		s.pending.Done()
+		result := &Result{Push: *push, State: Error, Description: "too many pending checks"}
+		s.recordResult(result)
+		go s.postStatus(result)
+		http.Error(w, "too many pending checks", http.StatusServiceUnavailable)
+	}
+}
+
+func (s *Server) handleResults(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	repository, branch := r.URL.Query().Get("repository"), r.URL.Query().Get("branch")
+	result := make([]*Result, 0)
+	for _, r := range s.Results() {
+		if (repository == "" || repository == r.Url || repository == r.Name) && (branch == "" || branch == r.Branch) {
+			result = append(result, r)
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		log.Printf("WARNING: failed to write results, %s", err)
+	}
+}
+
+// Results returns the latest result of every branch, ordered by repository and branch.
+func (s *Server) Results() []*Result {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	result := make([]*Result, 0, len(s.results))
+	for _, r := range s.results {
+		result = append(result, r)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Url != result[j].Url {
+			return result[i].Url < result[j].Url
+		}
+		return result[i].Branch < result[j].Branch
+	})
+	return result
+}
+
+// recordResult registers the result as the latest result of the branch.
+func (s *Server) recordResult(result *Result) {
+	result.Time = time.Now()
+	s.mutex.Lock()
+	s.results[result.Url+"\x00"+result.Branch] = result
+	s.mutex.Unlock()
+}
+
+// postStatus posts the result as commit status, if a status client is configured.
+func (s *Server) postStatus(result *Result) {
+	if s.options.Status == nil {
+		return
+	}
+	if err := s.options.Status.SetStatus(context.Background(), result); err != nil {
+		log.Printf("WARNING: %s", err)
+	}
+}
+
+// Run checks the received pushes until the context is done. The pending commit status is posted when the
+// check of a push starts, and the result when it is done.
+func (s *Server) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case push := <-s.queue:
+			s.postStatus(&Result{Push: *push, State: Pending, Description: pendingDescription, Time: time.Now()})
+			result := s.check(ctx, push)
+			s.recordResult(result)
+			s.postStatus(result)
+			s.pending.Done()
+		}
+	}
+}
+
+// Wait waits until all received pushes are checked.
+func (s *Server) Wait() {
+	s.pending.Wait()
+}
+
+// check clones the repository of the push, and checks the container image references in the pushed commit,
+// which need not be the tip of the branch anymore. The registries are consulted again for every check.
+func (s *Server) check(ctx context.Context, push *Push) *Result {
+	result := &Result{Push: *push}
+	log.Printf("INFO: checking %s on branch %s at %s", push.Url, push.Branch, push.Commit)
+
+	tag.ClearCaches()
+	r, err := repository.OpenReadOnly(ctx, push.Url, s.options.CacheDir, s.options.Verbose)
+	if err != nil {
+		log.Printf("WARNING: %s", err)
+		result.State, result.Description, result.Error = Error, "failed to check container image references", err.Error()
+		return result
+	}
+
+	options := s.options.Scan
+	options.Branches, options.RefPatterns = nil, []string{push.Commit}
+	references, err := scan.CheckReferences(ctx, r, options)
+	if err != nil {
+		log.Printf("WARNING: %s", err)
+		result.State, result.Description, result.Error = Error, "failed to check container image references", err.Error()
+		return result
+	}
+
+	for _, reference := range references {
+		reference.Branch = push.Branch
+	}
+	result.References = references
+	if len(references) == 0 {
+		result.State, result.Description = Success, "all container image references are up to date"
+	} else {
+		result.State = Failure
+		result.Description = fmt.Sprintf("%d out of date container image references: %s", len(references),
+			strings.Join(references.ExtractReferences(), ", "))
+	}
+	return result
+}
+
+// ListenAndServe serves the webhooks on the address and checks the pushes, until the context is done.
+func (s *Server) ListenAndServe(ctx context.Context, address string) error {
+	server := &http.Server{Addr: address, Handler: s}
+	go s.Run(ctx)
+	go func() {
+		<-ctx.Done()
+		_ = server.Shutdown(context.Background())
+	}()
+
+	log.Printf("INFO: listening for webhooks on %s", address)
+	if err := server.ListenAndServe(); err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
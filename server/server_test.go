@@ -0,0 +1,262 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/binxio/fromage/scan"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+const secret = "s3cr3t"
+
+type recordingStatus struct {
+	mutex  sync.Mutex
+	states []State
+}
+
+func (r *recordingStatus) SetStatus(ctx context.Context, result *Result) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.states = append(r.states, result.State)
+	return nil
+}
+
+func sign(body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestServer(t *testing.T) {
+	registryServer := httptest.NewServer(registry.New(registry.Logger(log.New(ioutil.Discard, "", 0))))
+	defer registryServer.Close()
+	u, _ := url.Parse(registryServer.URL)
+	for _, version := range []string{"3.16", "3.17"} {
+		image, err := random.Image(64, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		reference, _ := name.ParseReference(fmt.Sprintf("%s/library/alpine:%s", u.Host, version))
+		if err = remote.Write(reference, image); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	dir, err := ioutil.TempDir("", "fromage-server")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	source := filepath.Join(dir, "source")
+	g, err := git.PlainInit(source, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content := fmt.Sprintf("FROM %s/library/alpine:3.16\n", u.Host)
+	if err = ioutil.WriteFile(filepath.Join(source, "Dockerfile"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	worktree, _ := g.Worktree()
+	if _, err = worktree.Add("Dockerfile"); err != nil {
+		t.Fatal(err)
+	}
+	hash, err := worktree.Commit("add Dockerfile", &git.CommitOptions{
+		Author: &object.Signature{Name: "test", Email: "test@example.com", When: time.Now()},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	commit := hash.String()
+
+	// the tip of the branch is up to date, but the pushed commit is checked
+	content = fmt.Sprintf("FROM %s/library/alpine:3.17\n", u.Host)
+	if err = ioutil.WriteFile(filepath.Join(source, "Dockerfile"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = worktree.Add("Dockerfile"); err != nil {
+		t.Fatal(err)
+	}
+	_, err = worktree.Commit("update Dockerfile", &git.CommitOptions{
+		Author: &object.Signature{Name: "test", Email: "test@example.com", When: time.Now()},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	bare := filepath.Join(dir, "repository.git")
+	if _, err = git.PlainClone(bare, true, &git.CloneOptions{URL: source}); err != nil {
+		t.Fatal(err)
+	}
+
+	status := &recordingStatus{}
+	s := New(Options{Secret: secret, Scan: scan.Options{}, Status: status})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go s.Run(ctx)
+	server := httptest.NewServer(s)
+	defer server.Close()
+
+	body, _ := json.Marshal(map[string]interface{}{
+		"ref":   "refs/heads/master",
+		"after": commit,
+		"repository": map[string]string{
+			"full_name": "binxio/example",
+			"clone_url": bare,
+		},
+	})
+
+	var tests = []struct {
+		event     string
+		signature string
+		status    int
+	}{
+		{"push", "sha256=00", http.StatusUnauthorized},
+		{"ping", sign(body), http.StatusNoContent},
+		{"push", sign(body), http.StatusAccepted},
+	}
+	for _, test := range tests {
+		request, _ := http.NewRequest(http.MethodPost, server.URL+"/webhook", bytes.NewReader(body))
+		request.Header.Set("X-GitHub-Event", test.event)
+		request.Header.Set("X-Hub-Signature-256", test.signature)
+		response, err := http.DefaultClient.Do(request)
+		if err != nil {
+			t.Fatal(err)
+		}
+		response.Body.Close()
+		if response.StatusCode != test.status {
+			t.Fatalf("expected status %d for %s event, got %d", test.status, test.event, response.StatusCode)
+		}
+	}
+	s.Wait()
+
+	response, err := http.Get(server.URL + "/results?repository=binxio/example&branch=master")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer response.Body.Close()
+	var results []Result
+	if err = json.NewDecoder(response.Body).Decode(&results); err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("expected 1 result, got %d", len(results))
+	}
+	result := results[0]
+	if result.State != Failure || result.Commit != commit || len(result.References) != 1 {
+		t.Fatalf("expected a failure for %s with 1 reference, got %s for %s with %d: %s", commit, result.State,
+			result.Commit, len(result.References), result.Error)
+	}
+	if result.References[0].LatestMinor != "3.17" || result.References[0].Branch != "master" {
+		t.Fatalf("expected alpine 3.17 to be reported on master, got %v on %s", result.References[0].Newer,
+			result.References[0].Branch)
+	}
+
+	status.mutex.Lock()
+	states := status.states
+	status.mutex.Unlock()
+	if len(states) != 2 || states[0] != Pending || states[1] != Failure {
+		t.Fatalf("expected the commit status to be pending and failure, got %v", states)
+	}
+
+	// a commit which is not in the repository cannot be checked
+	unknown := "0123456789abcdef0123456789abcdef01234567"
+	body = bytes.Replace(body, []byte(commit), []byte(unknown), 1)
+	request, _ := http.NewRequest(http.MethodPost, server.URL+"/webhook", bytes.NewReader(body))
+	request.Header.Set("X-GitHub-Event", "push")
+	request.Header.Set("X-Hub-Signature-256", sign(body))
+	if response, err = http.DefaultClient.Do(request); err != nil {
+		t.Fatal(err)
+	}
+	response.Body.Close()
+	s.Wait()
+	if results := s.Results(); len(results) != 1 || results[0].State != Error || results[0].Commit != unknown {
+		t.Fatalf("expected an error for the unknown commit %s, got %v", unknown, results)
+	}
+}
+
+// blockingStatus blocks every status update until it is released.
+type blockingStatus struct {
+	release chan struct{}
+}
+
+func (b *blockingStatus) SetStatus(ctx context.Context, result *Result) error {
+	<-b.release
+	return nil
+}
+
+func TestWebhookDoesNotWaitForStatus(t *testing.T) {
+	status := &blockingStatus{release: make(chan struct{})}
+	s := New(Options{Secret: secret, Status: status})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go s.Run(ctx)
+	server := httptest.NewServer(s)
+	defer server.Close()
+
+	body := []byte(`{"ref":"refs/heads/main","after":"8f2e4c1","repository":{"full_name":"binxio/example","clone_url":"/nonexistent"}}`)
+	request, _ := http.NewRequest(http.MethodPost, server.URL+"/webhook", bytes.NewReader(body))
+	request.Header.Set("X-GitHub-Event", "push")
+	request.Header.Set("X-Hub-Signature-256", sign(body))
+	client := &http.Client{Timeout: 5 * time.Second}
+	response, err := client.Do(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusAccepted {
+		t.Fatalf("expected status %d, got %d", http.StatusAccepted, response.StatusCode)
+	}
+	if results := s.Results(); len(results) != 1 || results[0].State != Pending {
+		t.Fatalf("expected a pending result, got %v", results)
+	}
+	close(status.release)
+	s.Wait()
+}
+
+func TestParsePush(t *testing.T) {
+	gitlab := []byte(`{"object_kind":"push","ref":"refs/heads/main","after":"8f2e4c1","checkout_sha":"8f2e4c1",
+		"project":{"path_with_namespace":"binxio/example","git_http_url":"https://gitlab.com/binxio/example.git"}}`)
+	tag := []byte(`{"ref":"refs/tags/v1.0","after":"8f2e4c1","repository":{"full_name":"binxio/example","clone_url":"https://github.com/binxio/example.git"}}`)
+
+	var tests = []struct {
+		header http.Header
+		body   []byte
+		push   *Push
+		err    error
+	}{
+		{http.Header{"X-Gitlab-Event": {"Push Hook"}, "X-Gitlab-Token": {secret}}, gitlab,
+			&Push{"gitlab", "https://gitlab.com/binxio/example.git", "binxio/example", "main", "8f2e4c1"}, nil},
+		{http.Header{"X-Gitlab-Event": {"Push Hook"}, "X-Gitlab-Token": {"wrong"}}, gitlab, nil, fmt.Errorf("token mismatch")},
+		{http.Header{"X-Gitlab-Event": {"Tag Push Hook"}, "X-Gitlab-Token": {secret}}, gitlab, nil, errIgnored},
+		{http.Header{"X-Github-Event": {"push"}, "X-Hub-Signature-256": {sign(tag)}}, tag, nil, errIgnored},
+		{http.Header{}, tag, nil, fmt.Errorf("missing X-GitHub-Event or X-Gitlab-Event header")},
+	}
+	for i, test := range tests {
+		push, err := ParsePush(test.header, test.body, secret)
+		if (err == nil) != (test.err == nil) || (err != nil && err.Error() != test.err.Error()) {
+			t.Fatalf("expected error %v in test %d, got %v", test.err, i, err)
+		}
+		if test.push != nil && (push == nil || *push != *test.push) {
+			t.Fatalf("expected %v in test %d, got %v", test.push, i, push)
+		}
+	}
+}
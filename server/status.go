@@ -0,0 +1,117 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// maxDescription is the maximum length of the description of a commit status.
+const maxDescription = 140
+
+// StatusClient posts the result of a check as the status of the pushed commit.
+type StatusClient interface {
+	SetStatus(ctx context.Context, result *Result) error
+}
+
+// NewStatusClient returns the client for the commit status API of the provider, github or gitlab. If apiUrl
+// is empty, the API of github.com or gitlab.com is used.
+func NewStatusClient(provider string, apiUrl string, token string) (StatusClient, error) {
+	switch provider {
+	case "github":
+		if apiUrl == "" {
+			apiUrl = "https://api.github.com"
+		}
+		return &GitHubStatus{Url: strings.TrimRight(apiUrl, "/"), Token: token, Client: http.DefaultClient}, nil
+	case "gitlab":
+		if apiUrl == "" {
+			apiUrl = "https://gitlab.com"
+		}
+		return &GitLabStatus{Url: strings.TrimRight(apiUrl, "/"), Token: token, Client: http.DefaultClient}, nil
+	default:
+		return nil, fmt.Errorf("unsupported status provider %s, expected github or gitlab", provider)
+	}
+}
+
+// GitHubStatus posts commit statuses to the GitHub API at Url.
+type GitHubStatus struct {
+	Url    string
+	Token  string
+	Client *http.Client
+}
+
+// SetStatus creates a commit status with the context fromage.
+func (c *GitHubStatus) SetStatus(ctx context.Context, result *Result) error {
+	body, err := json.Marshal(map[string]string{
+		"state":       string(result.State),
+		"description": result.shortDescription(),
+		"context":     "fromage",
+	})
+	if err != nil {
+		return err
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost,
+		fmt.Sprintf("%s/repos/%s/statuses/%s", c.Url, result.Name, result.Commit), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Accept", "application/vnd.github+json")
+	request.Header.Set("Content-Type", "application/json")
+	if c.Token != "" {
+		request.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+	return send(c.Client, request)
+}
+
+// GitLabStatus posts commit statuses to the GitLab API at Url.
+type GitLabStatus struct {
+	Url    string
+	Token  string
+	Client *http.Client
+}
+
+// gitLabStates maps the states to the commit status states of GitLab.
+var gitLabStates = map[State]string{
+	Pending: "running",
+	Success: "success",
+	Failure: "failed",
+	Error:   "failed",
+}
+
+// SetStatus sets the commit status with the name fromage.
+func (c *GitLabStatus) SetStatus(ctx context.Context, result *Result) error {
+	query := url.Values{}
+	query.Set("state", gitLabStates[result.State])
+	query.Set("name", "fromage")
+	query.Set("ref", result.Branch)
+	query.Set("description", result.shortDescription())
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost,
+		fmt.Sprintf("%s/api/v4/projects/%s/statuses/%s?%s", c.Url, url.PathEscape(result.Name), result.Commit,
+			query.Encode()), nil)
+	if err != nil {
+		return err
+	}
+	if c.Token != "" {
+		request.Header.Set("PRIVATE-TOKEN", c.Token)
+	}
+	return send(c.Client, request)
+}
+
+func send(client *http.Client, request *http.Request) error {
+	response, err := client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode >= 300 {
+		message, _ := ioutil.ReadAll(response.Body)
+		return fmt.Errorf("failed to set commit status at %s, %s %s", request.URL.Host, response.Status,
+			strings.TrimSpace(string(message)))
+	}
+	return nil
+}
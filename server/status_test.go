@@ -0,0 +1,53 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestStatusClients(t *testing.T) {
+	var requests []*http.Request
+	var bodies []map[string]string
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body := map[string]string{}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		requests = append(requests, r)
+		bodies = append(bodies, body)
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer api.Close()
+
+	result := &Result{
+		Push:        Push{Name: "binxio/example", Branch: "main", Commit: "8f2e4c1"},
+		State:       Failure,
+		Description: "1 out of date container image references: alpine:3.16",
+	}
+	for _, provider := range []string{"github", "gitlab"} {
+		client, err := NewStatusClient(provider, api.URL, "token")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = client.SetStatus(context.Background(), result); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	github, gitlab := requests[0], requests[1]
+	if github.URL.Path != "/repos/binxio/example/statuses/8f2e4c1" || github.Header.Get("Authorization") != "Bearer token" {
+		t.Fatalf("unexpected GitHub request %s", github.URL)
+	}
+	if bodies[0]["state"] != "failure" || bodies[0]["context"] != "fromage" {
+		t.Fatalf("unexpected GitHub status %v", bodies[0])
+	}
+	if gitlab.URL.EscapedPath() != "/api/v4/projects/binxio%2Fexample/statuses/8f2e4c1" ||
+		gitlab.URL.Query().Get("state") != "failed" || gitlab.Header.Get("PRIVATE-TOKEN") != "token" {
+		t.Fatalf("unexpected GitLab request %s", gitlab.URL)
+	}
+
+	if _, err := NewStatusClient("bitbucket", "", ""); err == nil {
+		t.Fatalf("expected an error for an unsupported provider")
+	}
+}
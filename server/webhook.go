@@ -0,0 +1,124 @@
+package server
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"net/http"
+	"strings"
+)
+
+// Push is a push of a commit to a branch of a repository, as received from a webhook.
+type Push struct {
+	Provider string `json:"provider"`
+	Url      string `json:"repository"`
+	Name     string `json:"name"`
+	Branch   string `json:"branch"`
+	Commit   string `json:"commit"`
+}
+
+type gitHubPush struct {
+	Ref        string `json:"ref"`
+	After      string `json:"after"`
+	Deleted    bool   `json:"deleted"`
+	Repository struct {
+		FullName string `json:"full_name"`
+		CloneUrl string `json:"clone_url"`
+	} `json:"repository"`
+}
+
+type gitLabPush struct {
+	Ref         string `json:"ref"`
+	After       string `json:"after"`
+	CheckoutSha string `json:"checkout_sha"`
+	Project     struct {
+		PathWithNamespace string `json:"path_with_namespace"`
+		GitHttpUrl        string `json:"git_http_url"`
+	} `json:"project"`
+}
+
+// errIgnored indicates that the webhook is valid, but does not announce a push to a branch.
+var errIgnored = fmt.Errorf("event ignored")
+
+// verifyGitHub returns an error if the X-Hub-Signature-256 header is not the HMAC-SHA256 of the body
+// with the secret.
+func verifyGitHub(header http.Header, body []byte, secret string) error {
+	signature := header.Get("X-Hub-Signature-256")
+	if !strings.HasPrefix(signature, "sha256=") {
+		return fmt.Errorf("missing X-Hub-Signature-256 header")
+	}
+	expected, err := hex.DecodeString(strings.TrimPrefix(signature, "sha256="))
+	if err != nil {
+		return fmt.Errorf("invalid X-Hub-Signature-256 header, %s", err)
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	if !hmac.Equal(mac.Sum(nil), expected) {
+		return fmt.Errorf("signature mismatch")
+	}
+	return nil
+}
+
+// verifyGitLab returns an error if the X-Gitlab-Token header is not the secret.
+func verifyGitLab(header http.Header, secret string) error {
+	token := header.Get("X-Gitlab-Token")
+	if token == "" || subtle.ConstantTimeCompare([]byte(token), []byte(secret)) != 1 {
+		return fmt.Errorf("token mismatch")
+	}
+	return nil
+}
+
+// ParsePush verifies the webhook request with the secret, and returns the push it announces. GitHub
+// webhooks are verified by their HMAC-SHA256 signature, GitLab webhooks by their secret token. It returns
+// errIgnored for other events, like pings, tag pushes and deleted branches.
+func ParsePush(header http.Header, body []byte, secret string) (*Push, error) {
+	if event := header.Get("X-GitHub-Event"); event != "" {
+		if err := verifyGitHub(header, body, secret); err != nil {
+			return nil, err
+		}
+		if event != "push" {
+			return nil, errIgnored
+		}
+		var payload gitHubPush
+		if err := json.Unmarshal(body, &payload); err != nil {
+			return nil, fmt.Errorf("invalid push event, %s", err)
+		}
+		if payload.Deleted {
+			return nil, errIgnored
+		}
+		return newPush("github", payload.Repository.CloneUrl, payload.Repository.FullName, payload.Ref, payload.After)
+	}
+
+	if event := header.Get("X-Gitlab-Event"); event != "" {
+		if err := verifyGitLab(header, secret); err != nil {
+			return nil, err
+		}
+		if event != "Push Hook" {
+			return nil, errIgnored
+		}
+		var payload gitLabPush
+		if err := json.Unmarshal(body, &payload); err != nil {
+			return nil, fmt.Errorf("invalid push event, %s", err)
+		}
+		if payload.CheckoutSha == "" || plumbing.NewHash(payload.After).IsZero() {
+			return nil, errIgnored
+		}
+		return newPush("gitlab", payload.Project.GitHttpUrl, payload.Project.PathWithNamespace, payload.Ref, payload.CheckoutSha)
+	}
+	return nil, fmt.Errorf("missing X-GitHub-Event or X-Gitlab-Event header")
+}
+
+func newPush(provider, url, name, ref, commit string) (*Push, error) {
+	reference := plumbing.ReferenceName(ref)
+	if !reference.IsBranch() {
+		return nil, errIgnored
+	}
+	if url == "" || commit == "" {
+		return nil, fmt.Errorf("push event without repository or commit")
+	}
+	return &Push{Provider: provider, Url: url, Name: name, Branch: reference.Short(), Commit: commit}, nil
+}
@@ -0,0 +1,40 @@
+package tag
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/google/go-containerregistry/pkg/crane"
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"time"
+)
+
+var createdCache = map[string]time.Time{}
+
+// GetCreated returns the creation timestamp from the image configuration of the reference. For a
+// manifest list, it is the creation timestamp of the linux/amd64 image.
+func GetCreated(reference name.Reference) (time.Time, error) {
+	cacheMutex.Lock()
+	result, ok := createdCache[reference.Name()]
+	cacheMutex.Unlock()
+	if ok {
+		return result, nil
+	}
+
+	content, err := crane.Config(reference.Name())
+	if err != nil {
+		return time.Time{}, fmt.Errorf("could not retrieve image configuration of %s, %s", reference, err)
+	}
+	config, err := v1.ParseConfigFile(bytes.NewReader(content))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("could not read image configuration of %s, %s", reference, err)
+	}
+	if config.Created.IsZero() {
+		return time.Time{}, fmt.Errorf("image configuration of %s has no creation timestamp", reference)
+	}
+
+	cacheMutex.Lock()
+	createdCache[reference.Name()] = config.Created.Time
+	cacheMutex.Unlock()
+	return config.Created.Time, nil
+}
@@ -0,0 +1,48 @@
+package tag
+
+import (
+	"fmt"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"io/ioutil"
+	"log"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestGetCreated(t *testing.T) {
+	server := httptest.NewServer(registry.New(registry.Logger(log.New(ioutil.Discard, "", 0))))
+	defer server.Close()
+	u, _ := url.Parse(server.URL)
+
+	created := time.Date(2022, time.May, 23, 10, 0, 0, 0, time.UTC)
+	image, err := random.Image(64, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if image, err = mutate.CreatedAt(image, v1.Time{Time: created}); err != nil {
+		t.Fatal(err)
+	}
+	reference, _ := name.ParseReference(fmt.Sprintf("%s/library/alpine:3.16", u.Host))
+	if err = remote.Write(reference, image); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := GetCreated(reference)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Equal(created) {
+		t.Fatalf("expected %s, got %s", created, result)
+	}
+
+	if _, err = GetCreated(reference.Context().Tag("3.17")); err == nil {
+		t.Fatalf("expected an error for a non-existing image")
+	}
+}
@@ -0,0 +1,108 @@
+package tag
+
+import (
+	"fmt"
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"sort"
+	"strings"
+)
+
+// maxDigestComparisons limits the number of versioned tags of which the digest is compared to the digest of
+// a floating tag.
+const maxDigestComparisons = 64
+
+var digestCache = map[string]v1.Hash{}
+
+// movingTags are the tags which publishers move to new versions, optionally followed by a variant like -alpine.
+var movingTags = map[string]bool{
+	"latest": true, "edge": true, "stable": true, "lts": true, "current": true, "mainline": true,
+	"rolling": true, "nightly": true, "devel": true, "testing": true, "unstable": true,
+}
+
+// IsScratch returns true if the reference is the empty scratch image, which is not pulled from a registry.
+func IsScratch(reference name.Reference) bool {
+	return reference.Context().RegistryStr() == name.DefaultRegistry &&
+		reference.Context().RepositoryStr() == "library/scratch"
+}
+
+// IsFloating returns true if the reference has no tag, or a moving tag like latest, edge or lts-alpine.
+// These tags are moved to new versions by the publisher of the image. Codename tags like bookworm or slim,
+// and the scratch image, are not floating.
+func IsFloating(reference name.Reference) bool {
+	t, ok := reference.(name.Tag)
+	if !ok || IsScratch(reference) {
+		return false
+	}
+	moving := strings.ToLower(strings.SplitN(t.TagStr(), "-", 2)[0])
+	return movingTags[moving] && len(MakeTag(t.TagStr()).Version) == 0
+}
+
+// floatingVariants returns the suffixes of the versioned tags to which the floating tag may be equal, like
+// -alpine for lts-alpine, or -slim for slim.
+func floatingVariants(tag string) []string {
+	if i := strings.Index(tag, "-"); i >= 0 {
+		return []string{tag[i:], "-" + tag}
+	}
+	return []string{"", "-" + tag}
+}
+
+// GetDigest returns the digest of the manifest, or manifest list, of the reference.
+func GetDigest(reference name.Reference) (v1.Hash, error) {
+	cacheMutex.Lock()
+	result, ok := digestCache[reference.Name()]
+	cacheMutex.Unlock()
+	if ok {
+		return result, nil
+	}
+	descriptor, err := remote.Head(reference, remote.WithAuthFromKeychain(authn.DefaultKeychain))
+	if err != nil {
+		return v1.Hash{}, fmt.Errorf("could not retrieve digest of %s, %s", reference, err)
+	}
+	cacheMutex.Lock()
+	digestCache[reference.Name()] = descriptor.Digest
+	cacheMutex.Unlock()
+	return descriptor.Digest, nil
+}
+
+// ResolveFloating returns the versioned tag which currently refers to the same image as the floating
+// reference. If multiple versioned tags refer to the image, the most specific and highest version is returned.
+func ResolveFloating(reference name.Tag) (*name.Tag, error) {
+	digest, err := GetDigest(reference)
+	if err != nil {
+		return nil, err
+	}
+
+	tags, err := ListAllTags(reference.Context().String())
+	if err != nil {
+		return nil, err
+	}
+	variants := floatingVariants(reference.TagStr())
+	candidates := make(Tags, 0, len(tags))
+	for _, t := range tags {
+		if len(t.Version) > 0 && t.Prefix == "" && (t.Suffix == variants[0] || t.Suffix == variants[1]) {
+			candidates = append(candidates, t)
+		}
+	}
+	sort.Sort(sort.Reverse(candidates))
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return len(candidates[i].Version) > len(candidates[j].Version)
+	})
+
+	for i, candidate := range candidates {
+		if i == maxDigestComparisons {
+			break
+		}
+		next := updateIdentifier(reference, candidate.Literal)
+		d, err := GetDigest(next)
+		if err != nil {
+			return nil, err
+		}
+		if d == digest {
+			return &next, nil
+		}
+	}
+	return nil, fmt.Errorf("no versioned tag of %s refers to the image of %s", reference.Context(), reference.TagStr())
+}
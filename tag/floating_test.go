@@ -0,0 +1,93 @@
+package tag
+
+import (
+	"fmt"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"io/ioutil"
+	"log"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestIsFloating(t *testing.T) {
+	var tests = []struct {
+		reference string
+		floating  bool
+	}{
+		{"ubuntu", true},
+		{"node:latest", true},
+		{"alpine:edge", true},
+		{"node:lts-alpine", true},
+		{"node:20-alpine", false},
+		{"alpine:3.19", false},
+		{"scratch", false},
+		{"debian:bookworm", false},
+		{"debian:bookworm-slim", false},
+		{"python:slim", false},
+		{"node:current-alpine", true},
+		{"alpine@sha256:3ab1b26d7a1aad6b6d8a7e8d1e5b7b7e1a9f0a1c2e1c0d7e0f8e9a0b1c2d3e4f", false},
+	}
+	for _, test := range tests {
+		reference, err := name.ParseReference(test.reference)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if IsFloating(reference) != test.floating {
+			t.Fatalf("expected %s floating %v", test.reference, test.floating)
+		}
+	}
+}
+
+func TestResolveFloating(t *testing.T) {
+	server := httptest.NewServer(registry.New(registry.Logger(log.New(ioutil.Discard, "", 0))))
+	defer server.Close()
+	u, _ := url.Parse(server.URL)
+	repository := fmt.Sprintf("%s/library/alpine", u.Host)
+
+	push := func(tags ...string) {
+		image, err := random.Image(64, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, tag := range tags {
+			reference, _ := name.ParseReference(repository + ":" + tag)
+			if err = remote.Write(reference, image); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	push("3.18.5", "3.18")
+	push("3.19.1", "3.19", "3", "latest")
+	push("3.19.1-slim", "slim")
+	push("edge")
+
+	var tests = []struct {
+		reference string
+		resolved  string
+	}{
+		{repository, repository + ":3.19.1"},
+		{repository + ":latest", repository + ":3.19.1"},
+		{repository + ":slim", repository + ":3.19.1-slim"},
+		{repository + ":edge", ""},
+	}
+	for _, test := range tests {
+		reference, _ := name.NewTag(test.reference)
+		resolved, err := ResolveFloating(reference)
+		if test.resolved == "" {
+			if err == nil {
+				t.Fatalf("expected %s not to resolve, got %s", test.reference, resolved)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resolved.String() != test.resolved {
+			t.Fatalf("expected %s to resolve to %s, got %s", test.reference, test.resolved, resolved)
+		}
+	}
+}
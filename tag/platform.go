@@ -0,0 +1,251 @@
+package tag
+
+import (
+	"fmt"
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"log"
+	"strings"
+)
+
+type Platforms []v1.Platform
+
+var platformCache = map[string]Platforms{}
+
+// ParsePlatforms parses a comma separated list of platforms, like linux/amd64,linux/arm64/v8
+func ParsePlatforms(s string) (Platforms, error) {
+	result := make(Platforms, 0)
+	for _, p := range strings.Split(s, ",") {
+		if strings.TrimSpace(p) == "" {
+			continue
+		}
+		platform, err := v1.ParsePlatform(p)
+		if err != nil {
+			return nil, err
+		}
+		if platform.OS == "" || platform.Architecture == "" {
+			return nil, fmt.Errorf("%s is not a valid platform, expected os/arch[/variant]", p)
+		}
+		result = append(result, *platform)
+	}
+	return result, nil
+}
+
+func (l Platforms) String() string {
+	return strings.Join(l.Strings(), ",")
+}
+
+// Strings returns the string representation of each platform.
+func (l Platforms) Strings() []string {
+	result := make([]string, 0, len(l))
+	for _, p := range l {
+		result = append(result, p.String())
+	}
+	return result
+}
+
+// Merge returns the union of both platform lists.
+func (l Platforms) Merge(o Platforms) Platforms {
+	result := make(Platforms, 0, len(l)+len(o))
+	result = append(result, l...)
+	for _, p := range o {
+		if !result.Contains(p) {
+			result = append(result, p)
+		}
+	}
+	return result
+}
+
+// Satisfies returns true if the available platform a provides the required platform r. The
+// variant is only compared when it is specified in the required platform.
+func Satisfies(a, r v1.Platform) bool {
+	return a.OS == r.OS && a.Architecture == r.Architecture &&
+		(r.Variant == "" || a.Variant == r.Variant)
+}
+
+// Contains returns true if any of the platforms satisfies the required platform.
+func (l Platforms) Contains(required v1.Platform) bool {
+	for _, p := range l {
+		if Satisfies(p, required) {
+			return true
+		}
+	}
+	return false
+}
+
+// Missing returns the required platforms which are not provided by l.
+func (l Platforms) Missing(required Platforms) Platforms {
+	result := make(Platforms, 0)
+	for _, r := range required {
+		if !l.Contains(r) {
+			result = append(result, r)
+		}
+	}
+	return result
+}
+
+// GetPlatforms returns the platforms for which the image reference has a manifest. For
+// a manifest list, these are the platforms of the listed manifests. For a single image
+// manifest, it is the platform of the image configuration.
+func GetPlatforms(reference name.Reference) (Platforms, error) {
+	cacheMutex.Lock()
+	cached, ok := platformCache[reference.Name()]
+	cacheMutex.Unlock()
+	if ok {
+		return cached, nil
+	}
+
+	descriptor, err := remote.Get(reference, remote.WithAuthFromKeychain(authn.DefaultKeychain))
+	if err != nil {
+		return nil, fmt.Errorf("could not retrieve manifest for %s, %s", reference, err)
+	}
+
+	result := make(Platforms, 0)
+	if descriptor.MediaType.IsIndex() {
+		index, err := descriptor.ImageIndex()
+		if err != nil {
+			return nil, fmt.Errorf("could not read manifest list of %s, %s", reference, err)
+		}
+		manifest, err := index.IndexManifest()
+		if err != nil {
+			return nil, fmt.Errorf("could not read manifest list of %s, %s", reference, err)
+		}
+		for _, m := range manifest.Manifests {
+			if m.Platform != nil {
+				result = append(result, *m.Platform)
+			}
+		}
+	} else {
+		image, err := descriptor.Image()
+		if err != nil {
+			return nil, fmt.Errorf("could not read image manifest of %s, %s", reference, err)
+		}
+		config, err := image.ConfigFile()
+		if err != nil {
+			return nil, fmt.Errorf("could not read image configuration of %s, %s", reference, err)
+		}
+		result = append(result, v1.Platform{
+			OS:           config.OS,
+			Architecture: config.Architecture,
+			Variant:      config.Variant,
+		})
+	}
+
+	cacheMutex.Lock()
+	platformCache[reference.Name()] = result
+	cacheMutex.Unlock()
+	return result, nil
+}
+
+// GetMissingPlatforms returns the required platforms for which the image reference has no manifest.
+func GetMissingPlatforms(reference name.Reference, required Platforms) (Platforms, error) {
+	if len(required) == 0 {
+		return Platforms{}, nil
+	}
+	available, err := GetPlatforms(reference)
+	if err != nil {
+		return nil, err
+	}
+	return available.Missing(required), nil
+}
+
+// FilterByPlatforms returns the tags of the reference's repository which provide all the required platforms.
+// The platforms of every tag are retrieved, use FirstWithPlatforms or LastWithPlatforms if only one tag is
+// needed.
+func (l Tags) FilterByPlatforms(reference name.Tag, required Platforms) Tags {
+	if len(required) == 0 {
+		return l
+	}
+	result := make(Tags, 0, len(l))
+	for _, t := range l {
+		if providesPlatforms(reference, t, required) {
+			result = append(result, t)
+		}
+	}
+	return result
+}
+
+// FirstWithPlatforms returns the first tag of the reference's repository which provides all the required
+// platforms. The platforms are only retrieved until the tag is found.
+func (l Tags) FirstWithPlatforms(reference name.Tag, required Platforms) (Tag, bool) {
+	for _, t := range l {
+		if providesPlatforms(reference, t, required) {
+			return t, true
+		}
+	}
+	return Tag{}, false
+}
+
+// LastWithPlatforms returns the last tag of the reference's repository which provides all the required
+// platforms. The platforms are only retrieved until the tag is found.
+func (l Tags) LastWithPlatforms(reference name.Tag, required Platforms) (Tag, bool) {
+	for i := len(l) - 1; i >= 0; i-- {
+		if providesPlatforms(reference, l[i], required) {
+			return l[i], true
+		}
+	}
+	return Tag{}, false
+}
+
+// SuccessorsWithPlatforms returns the successors of the tag which are reported and provide all the required
+// platforms: the next successor, the latest successor on each version level and the latest newer variant.
+// The successors are expected to be sorted in ascending order. The platforms are only retrieved until these
+// are found, instead of for every successor.
+func (l Tags) SuccessorsWithPlatforms(reference name.Tag, tag Tag, required Platforms) Tags {
+	if len(required) == 0 {
+		return l
+	}
+
+	checked := make(map[int]bool)
+	provides := func(i int) bool {
+		result, ok := checked[i]
+		if !ok {
+			result = providesPlatforms(reference, l[i], required)
+			checked[i] = result
+		}
+		return result
+	}
+
+	selected := make(map[int]bool)
+	for i := range l {
+		if provides(i) {
+			selected[i] = true
+			break
+		}
+	}
+
+	// successors with the same version as the tag have a newer variant, these are grouped as level -1
+	found := make(map[Level]bool)
+	for i := len(l) - 1; i >= 0; i-- {
+		level, changed := ChangedLevel(tag, l[i])
+		if !changed {
+			level = -1
+		}
+		if !found[level] && provides(i) {
+			selected[i], found[level] = true, true
+		}
+	}
+
+	result := make(Tags, 0, len(selected))
+	for i, t := range l {
+		if selected[i] {
+			result = append(result, t)
+		}
+	}
+	return result
+}
+
+// providesPlatforms returns true if the tag of the reference's repository provides all the required platforms.
+func providesPlatforms(reference name.Tag, t Tag, required Platforms) bool {
+	if len(required) == 0 {
+		return true
+	}
+	missing, err := GetMissingPlatforms(reference.Tag(t.Literal), required)
+	if err != nil {
+		log.Printf("WARNING: skipping %s, %s", t.Literal, err)
+		return false
+	}
+	return len(missing) == 0
+}
@@ -0,0 +1,196 @@
+package tag
+
+import (
+	"fmt"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestParsePlatforms(t *testing.T) {
+	platforms, err := ParsePlatforms("linux/amd64, linux/arm64/v8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if platforms.String() != "linux/amd64,linux/arm64/v8" {
+		t.Fatalf("expected linux/amd64,linux/arm64/v8, got %s", platforms)
+	}
+
+	if _, err := ParsePlatforms("linux"); err == nil {
+		t.Fatalf("expected an error for a platform without architecture")
+	}
+}
+
+func TestPlatformsMissing(t *testing.T) {
+	available, _ := ParsePlatforms("linux/amd64,linux/arm64/v8,linux/arm/v7")
+	var tests = []struct {
+		required string
+		missing  string
+	}{
+		{"linux/amd64", ""},
+		{"linux/arm64", ""},
+		{"linux/arm64/v8", ""},
+		{"linux/arm/v6", "linux/arm/v6"},
+		{"linux/amd64,linux/s390x,windows/amd64", "linux/s390x,windows/amd64"},
+	}
+	for _, test := range tests {
+		required, _ := ParsePlatforms(test.required)
+		if missing := available.Missing(required); missing.String() != test.missing {
+			t.Fatalf("expected %s to be missing from %s, got %s", test.missing, test.required, missing)
+		}
+	}
+}
+
+func pushIndex(t *testing.T, reference string, platforms string) {
+	index := v1.ImageIndex(empty.Index)
+	required, _ := ParsePlatforms(platforms)
+	for i := range required {
+		image, err := random.Image(64, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		index = mutate.AppendManifests(index, mutate.IndexAddendum{
+			Add:        image,
+			Descriptor: v1.Descriptor{Platform: &required[i]},
+		})
+	}
+	ref, err := name.ParseReference(reference)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = remote.WriteIndex(ref, index); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGetNextVersionWithPlatforms(t *testing.T) {
+	server := httptest.NewServer(registry.New(registry.Logger(log.New(ioutil.Discard, "", 0))))
+	defer server.Close()
+	u, _ := url.Parse(server.URL)
+	repository := fmt.Sprintf("%s/library/alpine", u.Host)
+
+	pushIndex(t, repository+":3.16", "linux/amd64,linux/arm64")
+	pushIndex(t, repository+":3.17", "linux/amd64,linux/arm64")
+	pushIndex(t, repository+":3.18", "linux/amd64")
+
+	r, _ := name.ParseReference(repository + ":3.16")
+	reference, _ := r.(name.Tag)
+
+	var tests = []struct {
+		platforms string
+		output    string
+	}{
+		{"", "3.18"},
+		{"linux/amd64", "3.18"},
+		{"linux/amd64,linux/arm64", "3.17"},
+		{"linux/s390x", "3.16"},
+	}
+	for _, test := range tests {
+		platforms, _ := ParsePlatforms(test.platforms)
+		next, err := GetNextVersion(reference, nil, nil, true, platforms)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if next.TagStr() != test.output {
+			t.Fatalf("expected next version for %s to be %s, got %s", test.platforms, test.output, next.TagStr())
+		}
+	}
+
+	missing, err := GetMissingPlatforms(reference.Tag("3.18"), Platforms{{OS: "linux", Architecture: "arm64"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if missing.String() != "linux/arm64" {
+		t.Fatalf("expected linux/arm64 to be missing from 3.18, got %s", missing)
+	}
+}
+
+func TestGetNextVersionRetrievesPlatformsLazily(t *testing.T) {
+	var manifests int32
+	handler := registry.New(registry.Logger(log.New(ioutil.Discard, "", 0)))
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet && strings.Contains(r.URL.Path, "/manifests/") {
+			atomic.AddInt32(&manifests, 1)
+		}
+		handler.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+	u, _ := url.Parse(server.URL)
+	repository := fmt.Sprintf("%s/library/alpine", u.Host)
+
+	for _, version := range []string{"3.15", "3.16", "3.17", "3.18", "3.19"} {
+		pushIndex(t, repository+":"+version, "linux/amd64,linux/arm64")
+	}
+	r, _ := name.ParseReference(repository + ":3.15")
+	reference, _ := r.(name.Tag)
+	platforms, _ := ParsePlatforms("linux/arm64")
+
+	var tests = []struct {
+		latest bool
+		output string
+	}{
+		{false, "3.16"},
+		{true, "3.19"},
+	}
+	for _, test := range tests {
+		atomic.StoreInt32(&manifests, 0)
+		next, err := GetNextVersion(reference, nil, nil, test.latest, platforms)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if next.TagStr() != test.output {
+			t.Fatalf("expected next version %s, got %s", test.output, next.TagStr())
+		}
+		if n := atomic.LoadInt32(&manifests); n != 1 {
+			t.Fatalf("expected the manifest of only %s to be retrieved, got %d requests", test.output, n)
+		}
+	}
+}
+
+func TestGetAllSuccessorsRetrievesPlatformsOfReportedVersions(t *testing.T) {
+	var manifests int32
+	handler := registry.New(registry.Logger(log.New(ioutil.Discard, "", 0)))
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet && strings.Contains(r.URL.Path, "/manifests/") {
+			atomic.AddInt32(&manifests, 1)
+		}
+		handler.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+	u, _ := url.Parse(server.URL)
+	repository := fmt.Sprintf("%s/library/alpine", u.Host)
+
+	for _, version := range []string{"3.15.0", "3.15.1", "3.15.2", "3.16.0", "3.17.0", "3.18.0", "4.0.0"} {
+		pushIndex(t, repository+":"+version, "linux/amd64,linux/arm64")
+	}
+	pushIndex(t, repository+":4.1.0", "linux/amd64")
+	r, _ := name.ParseReference(repository + ":3.15.0")
+	platforms, _ := ParsePlatforms("linux/arm64")
+
+	successors, err := GetAllSuccessors(r, nil, nil, platforms)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := make([]string, 0, len(successors))
+	for _, successor := range successors {
+		result = append(result, successor.String())
+	}
+	if strings.Join(result, ",") != "3.15.1,3.15.2,3.18.0,4.0.0" {
+		t.Fatalf("expected the next and the latest version of each level, got %v", result)
+	}
+	if n := atomic.LoadInt32(&manifests); n != 5 {
+		t.Fatalf("expected the manifests of 3.15.1, 3.15.2, 3.18.0, 4.1.0 and 4.0.0 to be retrieved, got %d requests", n)
+	}
+}
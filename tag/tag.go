@@ -4,11 +4,14 @@ import (
 	"fmt"
 	"github.com/google/go-containerregistry/pkg/crane"
 	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
 	"log"
 	"regexp"
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 )
 
 type Level int
@@ -20,7 +23,7 @@ const (
 )
 
 func (l Level) String() string {
-	return [...]string{"MAJOR", "MINOR", "PATH"}[l]
+	return [...]string{"MAJOR", "MINOR", "PATCH"}[l]
 }
 
 func MakeLevelFromString(s string) (Level, error) {
@@ -36,12 +39,17 @@ func MakeLevelFromString(s string) (Level, error) {
 	}
 }
 
+// Tag is a version tag of a container image. Tags with a versioned variant suffix, like 1.21-alpine3.18,
+// are composite versions: VariantVersion holds the version of the variant, and all versions of the variant
+// share the same Category.
 type Tag struct {
-	Literal  string
-	Prefix   string
-	Suffix   string
-	Version  []int
-	Category string
+	Literal        string
+	Prefix         string
+	Suffix         string
+	Version        []int
+	Category       string
+	Variant        string
+	VariantVersion []int
 }
 
 type Tags []Tag
@@ -54,8 +62,23 @@ var (
 	gitDescribeSuffixRegExp      = regexp.MustCompile(`(?m)^-((?P<order>[0-9]+)-g)?(?P<sha>[0-9a-f]{6,})(?P<dirty>-dirty)?$`)
 	gitDescribeOrderSubExprIndex = findStringIndex(gitDescribeSuffixRegExp.SubexpNames(), "order")
 	tagListCache                 = map[string][]string{}
+
+	// cacheMutex guards the caches of the registry lookups, so that references can be looked up concurrently.
+	cacheMutex sync.Mutex
 )
 
+// ClearCaches forgets the tags, platforms, digests and creation timestamps retrieved from the registries, so
+// that a long running process sees the versions published since.
+func ClearCaches() {
+	cacheMutex.Lock()
+	defer cacheMutex.Unlock()
+	tagCategoryCache = map[string]TagCategories{}
+	tagListCache = map[string][]string{}
+	platformCache = map[string]Platforms{}
+	createdCache = map[string]time.Time{}
+	digestCache = map[string]v1.Hash{}
+}
+
 func findStringIndex(a []string, item string) int {
 	for i, s := range a {
 		if s == item {
@@ -99,6 +122,10 @@ func MakeTag(tag string) Tag {
 				result.Category = fmt.Sprintf("%s|%s", result.Prefix, "<git-describe>")
 			}
 
+		} else if variant, version, ok := parseVariant(result.Suffix); ok {
+			result.Variant = variant
+			result.VariantVersion = version
+			result.Category = fmt.Sprintf("%s|%s%s", result.Prefix, variant, "<version>")
 		} else {
 			result.Category = fmt.Sprintf("%s|%s", result.Prefix, result.Suffix)
 		}
@@ -167,8 +194,45 @@ func compareVersion(a []int, b []int) int {
 	return 0
 }
 
+// Compare compares the versions of the tags, and the versions of their variants if the versions are equal.
 func (a Tag) Compare(b Tag) int {
-	return compareVersion(a.Version, b.Version)
+	if result := compareVersion(a.Version, b.Version); result != 0 {
+		return result
+	}
+	return compareVersion(a.VariantVersion, b.VariantVersion)
+}
+
+// ChangedLevel returns the most significant version level in which the tags differ. It returns
+// false if the tags have the same version.
+func ChangedLevel(a, b Tag) (Level, bool) {
+	for i := 0; i < len(a.Version) && i < len(b.Version) && i <= PATCH; i++ {
+		if a.Version[i] != b.Version[i] {
+			return Level(i), true
+		}
+	}
+	if len(a.Version) != len(b.Version) {
+		level := len(a.Version)
+		if len(b.Version) < level {
+			level = len(b.Version)
+		}
+		if level > PATCH {
+			level = PATCH
+		}
+		return Level(level), true
+	}
+	return MAJOR, false
+}
+
+// LatestByLevel returns the latest successor of the tag for each version level in which it differs
+// from the tag. The successors are expected to be sorted in ascending order.
+func LatestByLevel(tag Tag, successors Tags) map[Level]Tag {
+	result := make(map[Level]Tag, 3)
+	for _, successor := range successors {
+		if level, changed := ChangedLevel(tag, successor); changed {
+			result[level] = successor
+		}
+	}
+	return result
 }
 
 func (a Tag) Equals(b Tag) bool {
@@ -176,7 +240,9 @@ func (a Tag) Equals(b Tag) bool {
 		a.Prefix == b.Prefix &&
 		a.Suffix == b.Suffix &&
 		a.Category == b.Category &&
-		compareVersion(a.Version, b.Version) == 0
+		a.Variant == b.Variant &&
+		compareVersion(a.Version, b.Version) == 0 &&
+		compareVersion(a.VariantVersion, b.VariantVersion) == 0
 }
 
 func ListAllTags(reference string) ([]Tag, error) {
@@ -213,7 +279,8 @@ func (l Tags) FilterByLevel(tag Tag, level Level) (result Tags) {
 func (l Tags) FindGreaterThan(tag Tag) Tags {
 	result := make(Tags, 0)
 	for _, t := range l {
-		if len(t.Version) == len(tag.Version) && tag.Compare(t) < 0 {
+		if len(t.Version) == len(tag.Version) && len(t.VariantVersion) == len(tag.VariantVersion) &&
+			compareVersion(tag.VariantVersion, t.VariantVersion) <= 0 && tag.Compare(t) < 0 {
 			result = append(result, t)
 		}
 	}
@@ -258,15 +325,18 @@ func MakeTagCategories(tags Tags) TagCategories {
 func GetTagsFromCache(reference name.Tag) (Tags, error) {
 	tag := MakeTag(reference.TagStr())
 	name := reference.Context().String()
+	cacheMutex.Lock()
 	categories, ok := tagCategoryCache[name]
+	cacheMutex.Unlock()
 	if !ok {
 		tagList, err := ListAllTags(name)
 		if err != nil {
-			tagList = Tags{}
 			return Tags{}, err
 		}
 		categories = MakeTagCategories(tagList)
+		cacheMutex.Lock()
 		tagCategoryCache[name] = categories
+		cacheMutex.Unlock()
 	}
 
 	tagList, ok := categories[tag.Category]
@@ -294,7 +364,9 @@ func updateIdentifier(reference name.Tag, identifier string) (next name.Tag) {
 	return next
 }
 
-func GetNextVersion(reference name.Tag, pin *Level, latest bool) (*name.Tag, error) {
+// GetNextVersion returns the next version of the reference, or the latest version if latest is true. The
+// version is pinned on the level of pin, and the version of the variant on the level of variantPin, if set.
+func GetNextVersion(reference name.Tag, pin *Level, variantPin *Level, latest bool, platforms Platforms) (*name.Tag, error) {
 	tagList, err := GetTagsFromCache(reference)
 	if err != nil {
 		log.Printf("WARNING: %s", err)
@@ -305,17 +377,23 @@ func GetNextVersion(reference name.Tag, pin *Level, latest bool) (*name.Tag, err
 	if pin != nil {
 		tagList = tagList.FilterByLevel(tag, *pin)
 	}
+	if variantPin != nil {
+		tagList = tagList.FilterByVariantLevel(tag, *variantPin)
+	}
 
-	if successors := tagList.FindGreaterThan(tag); len(successors) > 0 {
-		nextTag := updateIdentifier(reference, successors[0].Literal)
-		if latest {
-			nextTag = updateIdentifier(reference, successors[len(successors)-1].Literal)
-		}
-
+	successors := tagList.FindGreaterThan(tag)
+	next, found := successors.FirstWithPlatforms(reference, platforms)
+	if latest {
+		next, found = successors.LastWithPlatforms(reference, platforms)
+	}
+	if found {
+		nextTag := updateIdentifier(reference, next.Literal)
 		return &nextTag, nil
 	} else {
 		if len(tagList) > 1 {
-			if pin != nil {
+			if len(platforms) > 0 {
+				log.Printf("INFO: %s is at latest version available for %s", reference.String(), platforms)
+			} else if pin != nil {
 				log.Printf("INFO: %s is at latest %s version", reference.String(),
 					strings.ToLower(pin.String()))
 			} else {
@@ -335,13 +413,13 @@ func GetNextVersion(reference name.Tag, pin *Level, latest bool) (*name.Tag, err
 	return &reference, nil
 }
 
-func GetNextVersions(references []name.Reference, within *Level, latest bool) ([]name.Reference, error) {
+func GetNextVersions(references []name.Reference, within *Level, latest bool, platforms Platforms) ([]name.Reference, error) {
 	var errors = make([]error, 0)
 	var result = make([]name.Reference, 0, len(references))
 
 	for _, r := range references {
 		if ref, ok := r.(name.Tag); ok {
-			ref, err := GetNextVersion(ref, within, latest)
+			ref, err := GetNextVersion(ref, within, nil, latest, platforms)
 			if err != nil {
 				errors = append(errors, err)
 			}
@@ -357,15 +435,15 @@ func GetNextVersions(references []name.Reference, within *Level, latest bool) ([
 	}
 }
 
-func GetAllSuccessorsByString(reference string, pin *Level) ([]Tag, error) {
+func GetAllSuccessorsByString(reference string, pin *Level, variantPin *Level, platforms Platforms) ([]Tag, error) {
 	if r, err := name.ParseReference(reference); err == nil {
-		return GetAllSuccessors(r, pin)
+		return GetAllSuccessors(r, pin, variantPin, platforms)
 	} else {
 		return []Tag{}, err
 	}
 }
 
-func GetAllSuccessors(reference name.Reference, pin *Level) ([]Tag, error) {
+func GetAllSuccessors(reference name.Reference, pin *Level, variantPin *Level, platforms Platforms) ([]Tag, error) {
 	if r, ok := reference.(name.Tag); ok {
 		tagList, err := GetTagsFromCache(r)
 		if err != nil {
@@ -376,8 +454,11 @@ func GetAllSuccessors(reference name.Reference, pin *Level) ([]Tag, error) {
 		if pin != nil {
 			tagList = tagList.FilterByLevel(tag, *pin)
 		}
+		if variantPin != nil {
+			tagList = tagList.FilterByVariantLevel(tag, *variantPin)
+		}
 
-		return tagList.FindGreaterThan(tag), nil
+		return tagList.FindGreaterThan(tag).SuccessorsWithPlatforms(r, tag, platforms), nil
 
 	} else {
 		return []Tag{}, nil
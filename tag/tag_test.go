@@ -119,7 +119,7 @@ func TestGetNextVersion(t *testing.T) {
 	for _, test := range tests {
 		i, _ := name.ParseReference(test.input)
 		input, _ := i.(name.Tag)
-		output, err := GetNextVersion(input, test.limit, test.latest)
+		output, err := GetNextVersion(input, test.limit, nil, test.latest, nil)
 		if err == nil {
 			expect, _ := name.ParseReference(test.output)
 
@@ -177,7 +177,7 @@ func TestGetNextVersions(t *testing.T) {
 			}
 			o = append(o, ref)
 		}
-		output, err := GetNextVersions(i, nil, false)
+		output, err := GetNextVersions(i, nil, false, nil)
 		if test.error != (err != nil) {
 			t.Fatalf("expected error to be %v was %v", test.error, (err != nil))
 		}
@@ -193,3 +193,35 @@ func TestGetNextVersions(t *testing.T) {
 		}
 	}
 }
+
+func TestChangedLevel(t *testing.T) {
+	var tests = []struct {
+		a, b    string
+		level   Level
+		changed bool
+	}{
+		{"1.12", "2.0", MAJOR, true},
+		{"1.12", "1.13", MINOR, true},
+		{"1.12.1", "1.12.3", PATCH, true},
+		{"1.12", "1.12.1", PATCH, true},
+		{"7.2-fpm", "7.3-fpm", MINOR, true},
+		{"1.12", "1.12", MAJOR, false},
+	}
+	for _, test := range tests {
+		level, changed := ChangedLevel(MakeTag(test.a), MakeTag(test.b))
+		if changed != test.changed || (changed && level != test.level) {
+			t.Fatalf("expected %s -> %s to change %v on %s, got %v on %s", test.a, test.b, test.changed, test.level, changed, level)
+		}
+	}
+}
+
+func TestLatestByLevel(t *testing.T) {
+	successors := Tags{MakeTag("1.12.2"), MakeTag("1.12.3"), MakeTag("1.13.0"), MakeTag("1.14.1"), MakeTag("2.0.0")}
+	result := LatestByLevel(MakeTag("1.12.1"), successors)
+	expect := map[Level]string{MAJOR: "2.0.0", MINOR: "1.14.1", PATCH: "1.12.3"}
+	for level, literal := range expect {
+		if result[level].Literal != literal {
+			t.Fatalf("expected latest %s to be %s, got %s", level, literal, result[level].Literal)
+		}
+	}
+}
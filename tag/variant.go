@@ -0,0 +1,89 @@
+package tag
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	variantRegExp      = regexp.MustCompile(`^(?P<variant>\W.*[A-Za-z][-_]?)(?P<version>[0-9]+(\.[0-9]+)*)$`)
+	variantSubExpIndex = findStringIndex(variantRegExp.SubexpNames(), "variant")
+	versionSubExpIndex = findStringIndex(variantRegExp.SubexpNames(), "version")
+)
+
+// distributions are the operating system distributions of which the versions are variants of an image, like
+// alpine in 1.21-alpine3.18. Other versioned suffixes, like -jdk11 or -rc1, are part of the category of the tag,
+// so their version is never changed.
+var distributions = map[string]bool{
+	"alpine": true, "almalinux": true, "amazonlinux": true, "centos": true, "debian": true, "fedora": true,
+	"oraclelinux": true, "photon": true, "rockylinux": true, "ubi": true, "ubuntu": true,
+}
+
+// parseVariant splits a tag suffix like -alpine3.18 into the variant -alpine and its version. Only versioned
+// distributions are variants.
+func parseVariant(suffix string) (string, []int, bool) {
+	m := variantRegExp.FindStringSubmatch(suffix)
+	if m == nil {
+		return "", nil, false
+	}
+	words := strings.FieldsFunc(m[variantSubExpIndex], func(r rune) bool { return r == '-' || r == '_' })
+	if len(words) == 0 || !distributions[strings.ToLower(words[len(words)-1])] {
+		return "", nil, false
+	}
+	parts := strings.Split(m[versionSubExpIndex], ".")
+	version := make([]int, 0, len(parts))
+	for _, part := range parts {
+		level, _ := strconv.Atoi(part)
+		version = append(version, level)
+	}
+	return m[variantSubExpIndex], version, true
+}
+
+// IsComposite returns true if the tag has a versioned variant, like 1.21-alpine3.18.
+func (t Tag) IsComposite() bool {
+	return len(t.VariantVersion) > 0
+}
+
+// HasSameVariantLevel returns true if the versions of the variants of the tags are equal up to and including
+// the level.
+func HasSameVariantLevel(t, o Tag, level Level) bool {
+	if t.Category != o.Category {
+		return false
+	}
+	for i := 0; i <= int(level); i++ {
+		if i >= len(t.VariantVersion) || i >= len(o.VariantVersion) || t.VariantVersion[i] != o.VariantVersion[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// FilterByVariantLevel returns the tags with the same variant version as the tag on the level. If the tag has
+// no versioned variant, all tags are returned.
+func (l Tags) FilterByVariantLevel(tag Tag, level Level) Tags {
+	if !tag.IsComposite() {
+		return l
+	}
+	result := make(Tags, 0, len(l))
+	for _, t := range l {
+		if HasSameVariantLevel(t, tag, level) {
+			result = append(result, t)
+		}
+	}
+	return result
+}
+
+// LatestVariant returns the latest successor of the tag with the same version and a newer variant, like
+// 1.21-alpine3.19 for 1.21-alpine3.18. The successors are expected to be sorted in ascending order.
+func LatestVariant(tag Tag, successors Tags) (Tag, bool) {
+	var result Tag
+	var found bool
+	for _, successor := range successors {
+		if successor.IsComposite() && compareVersion(tag.Version, successor.Version) == 0 &&
+			compareVersion(tag.VariantVersion, successor.VariantVersion) < 0 {
+			result, found = successor, true
+		}
+	}
+	return result, found
+}
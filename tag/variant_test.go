@@ -0,0 +1,130 @@
+package tag
+
+import (
+	"fmt"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"io/ioutil"
+	"log"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestMakeCompositeTag(t *testing.T) {
+	var tests = []struct {
+		literal        string
+		variant        string
+		variantVersion []int
+		category       string
+	}{
+		{"1.21-alpine3.18", "-alpine", []int{3, 18}, "|-alpine<version>"},
+		{"8.2-fpm-alpine3.18", "-fpm-alpine", []int{3, 18}, "|-fpm-alpine<version>"},
+		{"17-jdk-ubi9", "-jdk-ubi", []int{9}, "|-jdk-ubi<version>"},
+		{"7.2-fpm", "", nil, "|-fpm"},
+		{"3.11-slim-bookworm", "", nil, "|-slim-bookworm"},
+		{"1.0-rc1", "", nil, "|-rc1"},
+		{"3.8-jdk11", "", nil, "|-jdk11"},
+		{"3.9-eclipse-temurin-17", "", nil, "|-eclipse-temurin-17"},
+		{"4.8-windowsservercore-ltsc2022", "", nil, "|-windowsservercore-ltsc2022"},
+		{"3.12-slim-debian12", "-slim-debian", []int{12}, "|-slim-debian<version>"},
+	}
+	for _, test := range tests {
+		tag := MakeTag(test.literal)
+		if tag.Variant != test.variant || compareVersion(tag.VariantVersion, test.variantVersion) != 0 ||
+			tag.Category != test.category {
+			t.Fatalf("expected %s to have variant %s %v in %s, got %s %v in %s", test.literal, test.variant,
+				test.variantVersion, test.category, tag.Variant, tag.VariantVersion, tag.Category)
+		}
+		if tag.String() != test.literal {
+			t.Fatalf("expected tag.String() to be %s, got %s", test.literal, tag.String())
+		}
+	}
+}
+
+func TestFindGreaterThanComposite(t *testing.T) {
+	tags := Tags{MakeTag("1.21-alpine3.17"), MakeTag("1.21-alpine3.18"), MakeTag("1.21-alpine3.19"),
+		MakeTag("1.22-alpine3.17"), MakeTag("1.22-alpine3.18"), MakeTag("1.22-alpine3.19")}
+	result := tags.FindGreaterThan(MakeTag("1.21-alpine3.18"))
+	expect := []string{"1.21-alpine3.19", "1.22-alpine3.18", "1.22-alpine3.19"}
+	if len(result) != len(expect) {
+		t.Fatalf("expected %v, got %v", expect, result)
+	}
+	for i, literal := range expect {
+		if result[i].Literal != literal {
+			t.Fatalf("expected %v, got %v", expect, result)
+		}
+	}
+
+	variant, ok := LatestVariant(MakeTag("1.21-alpine3.18"), result)
+	if !ok || variant.Literal != "1.21-alpine3.19" {
+		t.Fatalf("expected latest variant 1.21-alpine3.19, got %s", variant.Literal)
+	}
+	if _, ok = LatestVariant(MakeTag("1.21-alpine3.19"), tags.FindGreaterThan(MakeTag("1.21-alpine3.19"))); ok {
+		t.Fatalf("expected no newer variant of 1.21-alpine3.19")
+	}
+}
+
+func TestGetNextCompositeVersion(t *testing.T) {
+	server := httptest.NewServer(registry.New(registry.Logger(log.New(ioutil.Discard, "", 0))))
+	defer server.Close()
+	u, _ := url.Parse(server.URL)
+	repository := fmt.Sprintf("%s/library/golang", u.Host)
+
+	for _, literal := range []string{"1.21-alpine3.17", "1.21-alpine3.18", "1.21-alpine3.19", "1.22-alpine3.18",
+		"1.22-alpine3.19", "1.22-alpine4.0", "1.22-bookworm"} {
+		image, err := random.Image(64, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		reference, _ := name.ParseReference(fmt.Sprintf("%s:%s", repository, literal))
+		if err = remote.Write(reference, image); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	r, _ := name.ParseReference(repository + ":1.21-alpine3.18")
+	reference, _ := r.(name.Tag)
+
+	var major Level = MAJOR
+	var minor Level = MINOR
+	var tests = []struct {
+		pin        *Level
+		variantPin *Level
+		latest     bool
+		output     string
+	}{
+		{nil, nil, false, "1.21-alpine3.19"},
+		{nil, nil, true, "1.22-alpine4.0"},
+		{nil, &major, true, "1.22-alpine3.19"},
+		{nil, &minor, true, "1.22-alpine3.18"},
+		{&minor, nil, true, "1.21-alpine3.19"},
+		{&minor, &minor, true, "1.21-alpine3.18"},
+	}
+	for i, test := range tests {
+		next, err := GetNextVersion(reference, test.pin, test.variantPin, test.latest, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if next.TagStr() != test.output {
+			t.Fatalf("expected next version of test %d to be %s, got %s", i, test.output, next.TagStr())
+		}
+	}
+}
+
+func TestJdkSuffixIsNotVariant(t *testing.T) {
+	tags := Tags{MakeTag("3.8-jdk11"), MakeTag("3.8-jdk17"), MakeTag("3.9-jdk11"), MakeTag("3.9-jdk17")}
+	tag := MakeTag("3.8-jdk11")
+	if result := tags.FilterByLevel(tag, MINOR).FindGreaterThan(tag); len(result) != 0 {
+		t.Fatalf("expected no successor of %s with the minor version pinned, got %v", tag.Literal, result)
+	}
+	result := tags.FilterByLevel(tag, MAJOR).FindGreaterThan(tag)
+	if len(result) != 1 || result[0].Literal != "3.9-jdk11" {
+		t.Fatalf("expected only 3.9-jdk11 with the major version pinned, got %v", result)
+	}
+	if _, ok := LatestVariant(tag, tags.FindGreaterThan(tag)); ok {
+		t.Fatalf("expected no newer variant of %s", tag.Literal)
+	}
+}